@@ -4,9 +4,15 @@ import "github.com/polyscone/knight/token"
 
 // Binary represents an AST node that expects to hold two non-nil ast.Nodes.
 type Binary struct {
-	Op  token.Kind
-	LHS Node
-	RHS Node
+	Op   token.Kind
+	LHS  Node
+	RHS  Node
+	Span Span
+}
+
+// Location returns the region of source code the node was parsed from.
+func (b Binary) Location() Span {
+	return b.Span
 }
 
 // String prints a string form of Binary as an s-expression for testing.
@@ -26,10 +32,11 @@ func (b Binary) ASTString(style Style) string {
 
 // NewBinary returns a binary AST node that expects to hold two
 // non-nil ast.Nodes.
-func NewBinary(op token.Kind, lhs, rhs Node) Node {
+func NewBinary(span Span, op token.Kind, lhs, rhs Node) Node {
 	return &Binary{
-		Op:  op,
-		LHS: lhs,
-		RHS: rhs,
+		Op:   op,
+		LHS:  lhs,
+		RHS:  rhs,
+		Span: span,
 	}
 }
@@ -0,0 +1,360 @@
+package ast
+
+// Builtin holds the fields shared by every node that represents a call to one
+// of Knight's builtin functions.
+// Name is the function's name as it was written in the source code, like "I"
+// or "IF", and is only used for printing.
+type Builtin struct {
+	Name string
+	Span Span
+}
+
+// Location returns the region of source code the node was parsed from.
+func (b Builtin) Location() Span {
+	return b.Span
+}
+
+// ASCII represents a call to ASCII.
+type ASCII struct {
+	Builtin
+	Arg Node
+}
+
+// String prints a string form of ASCII as an s-expression for testing.
+func (n ASCII) String() string {
+	return n.ASTString(StyleSexpr)
+}
+
+// ASTString returns a string representation of the AST in the requested style.
+func (n ASCII) ASTString(style Style) string {
+	return SprintNode(style, n.Name, n.Arg.ASTString(style))
+}
+
+// NewASCII returns an ASCII AST node.
+func NewASCII(span Span, name string, arg Node) Node {
+	return &ASCII{Builtin: Builtin{Name: name, Span: span}, Arg: arg}
+}
+
+// Block represents a call to BLOCK.
+type Block struct {
+	Builtin
+	Body Node
+}
+
+// String prints a string form of Block as an s-expression for testing.
+func (n Block) String() string {
+	return n.ASTString(StyleSexpr)
+}
+
+// ASTString returns a string representation of the AST in the requested style.
+func (n Block) ASTString(style Style) string {
+	return SprintNode(style, n.Name, n.Body.ASTString(style))
+}
+
+// NewBlock returns a Block AST node.
+func NewBlock(span Span, name string, body Node) Node {
+	return &Block{Builtin: Builtin{Name: name, Span: span}, Body: body}
+}
+
+// CallBlock represents a call to CALL.
+// It's not named Call because that's the generic function call node.
+type CallBlock struct {
+	Builtin
+	Block Node
+}
+
+// String prints a string form of CallBlock as an s-expression for testing.
+func (n CallBlock) String() string {
+	return n.ASTString(StyleSexpr)
+}
+
+// ASTString returns a string representation of the AST in the requested style.
+func (n CallBlock) ASTString(style Style) string {
+	return SprintNode(style, n.Name, n.Block.ASTString(style))
+}
+
+// NewCallBlock returns a CallBlock AST node.
+func NewCallBlock(span Span, name string, block Node) Node {
+	return &CallBlock{Builtin: Builtin{Name: name, Span: span}, Block: block}
+}
+
+// Dump represents a call to DUMP.
+type Dump struct {
+	Builtin
+	Arg Node
+}
+
+// String prints a string form of Dump as an s-expression for testing.
+func (n Dump) String() string {
+	return n.ASTString(StyleSexpr)
+}
+
+// ASTString returns a string representation of the AST in the requested style.
+func (n Dump) ASTString(style Style) string {
+	return SprintNode(style, n.Name, n.Arg.ASTString(style))
+}
+
+// NewDump returns a Dump AST node.
+func NewDump(span Span, name string, arg Node) Node {
+	return &Dump{Builtin: Builtin{Name: name, Span: span}, Arg: arg}
+}
+
+// Eval represents a call to EVAL.
+type Eval struct {
+	Builtin
+	Arg Node
+}
+
+// String prints a string form of Eval as an s-expression for testing.
+func (n Eval) String() string {
+	return n.ASTString(StyleSexpr)
+}
+
+// ASTString returns a string representation of the AST in the requested style.
+func (n Eval) ASTString(style Style) string {
+	return SprintNode(style, n.Name, n.Arg.ASTString(style))
+}
+
+// NewEval returns an Eval AST node.
+func NewEval(span Span, name string, arg Node) Node {
+	return &Eval{Builtin: Builtin{Name: name, Span: span}, Arg: arg}
+}
+
+// Get represents a call to GET.
+type Get struct {
+	Builtin
+	Str   Node
+	Start Node
+	Count Node
+}
+
+// String prints a string form of Get as an s-expression for testing.
+func (n Get) String() string {
+	return n.ASTString(StyleSexpr)
+}
+
+// ASTString returns a string representation of the AST in the requested style.
+func (n Get) ASTString(style Style) string {
+	return SprintNode(
+		style,
+		n.Name,
+		n.Str.ASTString(style),
+		n.Start.ASTString(style),
+		n.Count.ASTString(style),
+	)
+}
+
+// NewGet returns a Get AST node.
+func NewGet(span Span, name string, str, start, count Node) Node {
+	return &Get{
+		Builtin: Builtin{Name: name, Span: span},
+		Str:     str,
+		Start:   start,
+		Count:   count,
+	}
+}
+
+// If represents a call to IF.
+type If struct {
+	Builtin
+	Condition   Node
+	Consequence Node
+	Alternative Node
+}
+
+// String prints a string form of If as an s-expression for testing.
+func (n If) String() string {
+	return n.ASTString(StyleSexpr)
+}
+
+// ASTString returns a string representation of the AST in the requested style.
+func (n If) ASTString(style Style) string {
+	return SprintNode(
+		style,
+		n.Name,
+		n.Condition.ASTString(style),
+		n.Consequence.ASTString(style),
+		n.Alternative.ASTString(style),
+	)
+}
+
+// NewIf returns an If AST node.
+func NewIf(span Span, name string, condition, consequence, alternative Node) Node {
+	return &If{
+		Builtin:     Builtin{Name: name, Span: span},
+		Condition:   condition,
+		Consequence: consequence,
+		Alternative: alternative,
+	}
+}
+
+// Length represents a call to LENGTH.
+type Length struct {
+	Builtin
+	Arg Node
+}
+
+// String prints a string form of Length as an s-expression for testing.
+func (n Length) String() string {
+	return n.ASTString(StyleSexpr)
+}
+
+// ASTString returns a string representation of the AST in the requested style.
+func (n Length) ASTString(style Style) string {
+	return SprintNode(style, n.Name, n.Arg.ASTString(style))
+}
+
+// NewLength returns a Length AST node.
+func NewLength(span Span, name string, arg Node) Node {
+	return &Length{Builtin: Builtin{Name: name, Span: span}, Arg: arg}
+}
+
+// Output represents a call to OUTPUT.
+type Output struct {
+	Builtin
+	Arg Node
+}
+
+// String prints a string form of Output as an s-expression for testing.
+func (n Output) String() string {
+	return n.ASTString(StyleSexpr)
+}
+
+// ASTString returns a string representation of the AST in the requested style.
+func (n Output) ASTString(style Style) string {
+	return SprintNode(style, n.Name, n.Arg.ASTString(style))
+}
+
+// NewOutput returns an Output AST node.
+func NewOutput(span Span, name string, arg Node) Node {
+	return &Output{Builtin: Builtin{Name: name, Span: span}, Arg: arg}
+}
+
+// Prompt represents a call to PROMPT.
+type Prompt struct {
+	Builtin
+}
+
+// String prints a string form of Prompt as an s-expression for testing.
+func (n Prompt) String() string {
+	return n.ASTString(StyleSexpr)
+}
+
+// ASTString returns a string representation of the AST in the requested style.
+func (n Prompt) ASTString(style Style) string {
+	return n.Name
+}
+
+// NewPrompt returns a Prompt AST node.
+func NewPrompt(span Span, name string) Node {
+	return &Prompt{Builtin: Builtin{Name: name, Span: span}}
+}
+
+// Quit represents a call to QUIT.
+type Quit struct {
+	Builtin
+	Arg Node
+}
+
+// String prints a string form of Quit as an s-expression for testing.
+func (n Quit) String() string {
+	return n.ASTString(StyleSexpr)
+}
+
+// ASTString returns a string representation of the AST in the requested style.
+func (n Quit) ASTString(style Style) string {
+	return SprintNode(style, n.Name, n.Arg.ASTString(style))
+}
+
+// NewQuit returns a Quit AST node.
+func NewQuit(span Span, name string, arg Node) Node {
+	return &Quit{Builtin: Builtin{Name: name, Span: span}, Arg: arg}
+}
+
+// Random represents a call to RANDOM.
+type Random struct {
+	Builtin
+}
+
+// String prints a string form of Random as an s-expression for testing.
+func (n Random) String() string {
+	return n.ASTString(StyleSexpr)
+}
+
+// ASTString returns a string representation of the AST in the requested style.
+func (n Random) ASTString(style Style) string {
+	return n.Name
+}
+
+// NewRandom returns a Random AST node.
+func NewRandom(span Span, name string) Node {
+	return &Random{Builtin: Builtin{Name: name, Span: span}}
+}
+
+// Substitute represents a call to SUBSTITUTE.
+type Substitute struct {
+	Builtin
+	Str         Node
+	Start       Node
+	Count       Node
+	Replacement Node
+}
+
+// String prints a string form of Substitute as an s-expression for testing.
+func (n Substitute) String() string {
+	return n.ASTString(StyleSexpr)
+}
+
+// ASTString returns a string representation of the AST in the requested style.
+func (n Substitute) ASTString(style Style) string {
+	return SprintNode(
+		style,
+		n.Name,
+		n.Str.ASTString(style),
+		n.Start.ASTString(style),
+		n.Count.ASTString(style),
+		n.Replacement.ASTString(style),
+	)
+}
+
+// NewSubstitute returns a Substitute AST node.
+func NewSubstitute(span Span, name string, str, start, count, replacement Node) Node {
+	return &Substitute{
+		Builtin:     Builtin{Name: name, Span: span},
+		Str:         str,
+		Start:       start,
+		Count:       count,
+		Replacement: replacement,
+	}
+}
+
+// While represents a call to WHILE.
+type While struct {
+	Builtin
+	Condition Node
+	Body      Node
+}
+
+// String prints a string form of While as an s-expression for testing.
+func (n While) String() string {
+	return n.ASTString(StyleSexpr)
+}
+
+// ASTString returns a string representation of the AST in the requested style.
+func (n While) ASTString(style Style) string {
+	return SprintNode(
+		style,
+		n.Name,
+		n.Condition.ASTString(style),
+		n.Body.ASTString(style),
+	)
+}
+
+// NewWhile returns a While AST node.
+func NewWhile(span Span, name string, condition, body Node) Node {
+	return &While{
+		Builtin:   Builtin{Name: name, Span: span},
+		Condition: condition,
+		Body:      body,
+	}
+}
@@ -10,6 +10,12 @@ type Call struct {
 	Name   string
 	Letter byte
 	Args   []Node
+	Span   Span
+}
+
+// Location returns the region of source code the node was parsed from.
+func (c Call) Location() Span {
+	return c.Span
 }
 
 // Dump prints a string form of Call for testing.
@@ -46,7 +52,7 @@ func (c Call) ASTString(style Style) string {
 // NewCall returns a Call AST node that represents a call with the given
 // arguments to a function.
 // Args are provided as a slice, but there is an upper-limit of four args.
-func NewCall(name string, args []Node) Node {
+func NewCall(span Span, name string, args []Node) Node {
 	if len(args) > 4 {
 		panic("too many args")
 	}
@@ -55,5 +61,6 @@ func NewCall(name string, args []Node) Node {
 		Name:   name,
 		Letter: name[0],
 		Args:   args,
+		Span:   span,
 	}
 }
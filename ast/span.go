@@ -0,0 +1,48 @@
+package ast
+
+import "fmt"
+
+// Span describes the region of source code that an AST node was parsed from.
+// Lines and columns are 1-based, and the end position is exclusive.
+type Span struct {
+	File    string
+	Line    int
+	Col     int
+	EndLine int
+	EndCol  int
+}
+
+// String returns the start of the span in the conventional file:line:col form.
+// If the span has no file name then only the line and column are returned.
+func (s Span) String() string {
+	if s.File == "" {
+		return fmt.Sprintf("%v:%v", s.Line, s.Col)
+	}
+
+	return fmt.Sprintf("%v:%v:%v", s.File, s.Line, s.Col)
+}
+
+// IsZero reports whether the span is empty, which is the case for nodes that
+// were not created by a parser.
+func (s Span) IsZero() bool {
+	return s.Line == 0
+}
+
+// Locatable is implemented by AST nodes that know which region of source code
+// they were parsed from.
+//
+// Literal leaf values are interned and shared between every place they
+// appear in a program, so they don't implement this interface; their location
+// is described by the span of the node that encloses them.
+type Locatable interface {
+	Location() Span
+}
+
+// Locate returns the span of the given node, if it has one.
+func Locate(node Node) (Span, bool) {
+	if l, ok := node.(Locatable); ok {
+		return l.Location(), true
+	}
+
+	return Span{}, false
+}
@@ -10,6 +10,12 @@ import (
 type Unary struct {
 	Op   token.Kind
 	Node Node
+	Span Span
+}
+
+// Location returns the region of source code the node was parsed from.
+func (u Unary) Location() Span {
+	return u.Span
 }
 
 // Dump prints a string form of Unary for testing.
@@ -30,9 +36,10 @@ func (u Unary) ASTString(style Style) string {
 }
 
 // NewUnary returns a Unary AST node.
-func NewUnary(op token.Kind, node Node) Node {
+func NewUnary(span Span, op token.Kind, node Node) Node {
 	return &Unary{
 		Op:   op,
 		Node: node,
+		Span: span,
 	}
 }
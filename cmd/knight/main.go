@@ -2,22 +2,27 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
 	"runtime"
 	"runtime/pprof"
 	"runtime/trace"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/polyscone/knight/ast"
 	"github.com/polyscone/knight/interpreter"
 	"github.com/polyscone/knight/lexer"
+	"github.com/polyscone/knight/optimize"
 	"github.com/polyscone/knight/parser"
+	"github.com/polyscone/knight/transpile"
 	"github.com/polyscone/knight/value"
+	"github.com/polyscone/knight/vm"
 )
 
 var (
@@ -34,6 +39,18 @@ var opts struct {
 	filename   string
 	profile    string
 	astStyle   string
+	maxSteps   int
+	maxDepth   int
+	timeout    time.Duration
+	system     string
+	seed       int64
+	backend    string
+	optimize   int
+	cacheLimit int
+	cacheStats bool
+	strict     bool
+	ints       string
+	dump       string
 	version    bool
 }
 
@@ -42,6 +59,18 @@ func main() {
 	flag.StringVar(&opts.filename, "f", "", "A path to a file to run")
 	flag.StringVar(&opts.profile, "p", "", "The name of a profile to record")
 	flag.StringVar(&opts.astStyle, "a", "", `Print the program's AST; available styles are: "sexpr", "tree", and "waterfall"`)
+	flag.IntVar(&opts.maxSteps, "max-steps", 0, "The maximum number of nodes to evaluate before aborting; 0 means no limit")
+	flag.IntVar(&opts.maxDepth, "max-depth", interpreter.DefaultMaxDepth, "The maximum depth of nested calls before aborting; 0 means no limit")
+	flag.DurationVar(&opts.timeout, "timeout", 0, "The maximum amount of time to run for before aborting, e.g. 5s; 0 means no limit")
+	flag.StringVar(&opts.system, "system", "shell", `How SYSTEM runs commands; available modes are: "shell", "deny", and "allow:name,name,..."`)
+	flag.Int64Var(&opts.seed, "seed", 0, "The seed for RANDOM; a time based seed is used by default and printed if the program fails")
+	flag.StringVar(&opts.backend, "backend", "tree", `How programs are executed; available backends are: "tree" and "vm"`)
+	flag.IntVar(&opts.optimize, "O", 0, "The optimisation level; 0 disables optimisation, 1 simplifies the AST, and 2 also folds constants")
+	flag.IntVar(&opts.cacheLimit, "cache-limit", value.DefaultCacheLimit, "The maximum number of entries in each of the value interning caches; 0 disables interning")
+	flag.BoolVar(&opts.cacheStats, "cache-stats", false, "Print value interning cache stats to stderr when the program finishes")
+	flag.BoolVar(&opts.strict, "strict", false, "Report anything that the Knight spec leaves undefined as an error")
+	flag.StringVar(&opts.ints, "int", "native", `How integer arithmetic overflows; available semantics are: "native", "wrap32", "checked64", and "big"`)
+	flag.StringVar(&opts.dump, "dump", "legacy", `How DUMP prints values; available formats are: "legacy" and "spec"`)
 	flag.BoolVar(&opts.version, "version", false, "Display binary version information")
 	flag.Parse()
 
@@ -58,13 +87,24 @@ func main() {
 		return
 	}
 
+	if flag.Arg(0) == "build" {
+		os.Exit(build(flag.Args()[1:]))
+	}
+
 	if (opts.expression == "" && opts.filename == "") || (opts.expression != "" && opts.filename != "") {
 		flag.Usage()
 
 		os.Exit(2)
 	}
 
-	//nolint:gocritic // it's ok for defers to not run in profile code
+	os.Exit(run())
+}
+
+// run executes the program given on the command line and returns the exit
+// status for the process.
+// It's separate from main so that deferred calls, like the ones that stop
+// profiling, always run before the process exits.
+func run() int {
 	switch opts.profile {
 	case "cpu":
 		f, err := os.Create("cpu.pprof")
@@ -105,6 +145,16 @@ func main() {
 		}
 	}
 
+	rt := interpreter.NewRuntime(nil)
+	heap := rt.Heap()
+	for c := value.CacheStrings; c <= value.CacheInts; c++ {
+		heap.SetCacheLimit(c, opts.cacheLimit)
+	}
+
+	if opts.cacheStats {
+		defer printCacheStats(rt)
+	}
+
 	var b []byte
 	if opts.expression != "" {
 		b = []byte(opts.expression)
@@ -113,20 +163,28 @@ func main() {
 		if b, err = os.ReadFile(opts.filename); err != nil {
 			fmt.Println(err)
 
-			os.Exit(1)
+			return 1
 		}
 	}
 
 	l := lexer.New()
-	p := parser.New(l)
+	p := parser.New(l, parser.WithHeap(heap))
 	g := value.NewGlobalStore()
-	program, err := p.Parse(g, bytes.NewReader(b))
+	program, err := p.ParseFile(g, opts.filename, bytes.NewReader(b))
 	if err != nil {
 		fmt.Println(err)
 
-		os.Exit(1)
+		return 1
+	}
+
+	if opts.optimize < int(optimize.None) || opts.optimize > int(optimize.Fold) {
+		flag.Usage()
+
+		return 2
 	}
 
+	program = optimize.Program(program, optimize.Level(opts.optimize))
+
 	if opts.astStyle != "" {
 		switch opts.astStyle {
 		case "sexpr":
@@ -138,17 +196,197 @@ func main() {
 		default:
 			flag.Usage()
 
-			os.Exit(2)
+			return 2
 		}
 
-		return
+		return 0
+	}
+
+	ctx := context.Background()
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	var runner interpreter.CommandRunner
+	switch {
+	case opts.system == "shell":
+		runner = interpreter.ShellRunner{}
+	case opts.system == "deny":
+		runner = interpreter.DenyRunner{}
+	case strings.HasPrefix(opts.system, "allow:"):
+		names := strings.Split(strings.TrimPrefix(opts.system, "allow:"), ",")
+		runner = interpreter.AllowlistRunner{Names: names}
+	default:
+		flag.Usage()
+
+		return 2
+	}
+
+	ints, err := interpreter.ParseIntSemantics(opts.ints)
+	if err != nil {
+		flag.Usage()
+
+		return 2
+	}
+
+	dump, err := interpreter.ParseDumpFormat(opts.dump)
+	if err != nil {
+		flag.Usage()
+
+		return 2
+	}
+
+	iopts := []interpreter.Option{
+		interpreter.WithMaxSteps(opts.maxSteps),
+		interpreter.WithMaxDepth(opts.maxDepth),
+		interpreter.WithCommandRunner(runner),
+		interpreter.WithRuntime(rt),
+		interpreter.WithStrict(opts.strict),
+		interpreter.WithIntSemantics(ints),
+		interpreter.WithDumpFormat(dump),
+	}
+
+	switch opts.backend {
+	case "tree":
+		// The interpreter walks the tree by default
+	case "vm":
+		iopts = append(iopts, interpreter.WithBackend(vm.New()))
+	default:
+		flag.Usage()
+
+		return 2
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			iopts = append(iopts, interpreter.WithSeed(opts.seed))
+		}
+	})
+
+	i := interpreter.New(g, p, iopts...)
+	if _, err := i.ExecuteContext(ctx, program); err != nil {
+		var exit *interpreter.ExitError
+		if errors.As(err, &exit) {
+			return exit.Code
+		}
+
+		fmt.Println(err)
+
+		var rerr *interpreter.RuntimeError
+		if errors.As(err, &rerr) {
+			fmt.Print("\nknight stack:\n", rerr.StackTrace())
+		}
+
+		if seed, ok := i.Seed(); ok && i.RandomUsed() {
+			fmt.Printf("\nrandom seed: %v (use -seed %v to replay)\n", seed, seed)
+		}
+
+		return 1
+	}
+
+	return 0
+}
+
+// build transpiles the program given by the build subcommand's arguments to
+// the source code of a Go main package and returns the exit status for the
+// process.
+func build(args []string) int {
+	var expression, filename, output, ints, dump string
+	var level int
+	var strict bool
+
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	fs.StringVar(&expression, "e", "", "An expression to transpile")
+	fs.StringVar(&filename, "f", "", "A path to a file to transpile")
+	fs.StringVar(&output, "o", "", "A path to write the Go source code to; it's written to stdout by default")
+	fs.IntVar(&level, "O", 0, "The optimisation level; 0 disables optimisation, 1 simplifies the AST, and 2 also folds constants")
+	fs.BoolVar(&strict, "strict", false, "Not supported; transpiled programs are never strict")
+	fs.StringVar(&ints, "int", "native", `Not supported; transpiled programs always use "native" integers`)
+	fs.StringVar(&dump, "dump", "legacy", `Not supported; transpiled programs always use the "legacy" DUMP format`)
+	//nolint:errcheck // the flag set exits on error
+	fs.Parse(args)
+
+	if (expression == "" && filename == "") || (expression != "" && filename != "") {
+		fs.Usage()
+
+		return 2
+	}
+
+	if level < int(optimize.None) || level > int(optimize.Fold) {
+		fs.Usage()
+
+		return 2
+	}
+
+	// The generated prelude always creates an interpreter with the default
+	// semantics, so rather than silently building a program that behaves
+	// differently to how it would be run these are rejected
+	if strict || ints != interpreter.IntNative.String() || dump != interpreter.DumpLegacy.String() {
+		fmt.Println("build: -strict, -int, and -dump aren't supported; transpiled programs always use native integers, the legacy DUMP format, and aren't strict")
+
+		return 2
+	}
+
+	b := []byte(expression)
+	if filename != "" {
+		var err error
+		if b, err = os.ReadFile(filename); err != nil {
+			fmt.Println(err)
+
+			return 1
+		}
 	}
 
-	rand.Seed(time.Now().UnixNano())
+	p := parser.New(lexer.New())
+	program, err := p.ParseFile(value.NewGlobalStore(), filename, bytes.NewReader(b))
+	if err != nil {
+		fmt.Println(err)
+
+		return 1
+	}
+
+	program = optimize.Program(program, optimize.Level(level))
 
-	if _, err := interpreter.New(g, p).Execute(program); err != nil {
+	src, err := transpile.Program(program)
+	if err != nil {
 		fmt.Println(err)
 
-		os.Exit(1)
+		return 1
+	}
+
+	if output == "" {
+		if _, err := os.Stdout.Write(src); err != nil {
+			fmt.Println(err)
+
+			return 1
+		}
+
+		return 0
 	}
+
+	if err := os.WriteFile(output, src, 0o644); err != nil {
+		fmt.Println(err)
+
+		return 1
+	}
+
+	return 0
+}
+
+// printCacheStats prints the stats for each of the runtime's interning caches,
+// and its EVAL program cache, to stderr.
+func printCacheStats(rt *interpreter.Runtime) {
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "cache\tentries\tlimit\thits\tmisses\tevictions")
+
+	for _, stats := range rt.Heap().Stats() {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", stats.Cache, stats.Len, stats.Limit, stats.Hits, stats.Misses, stats.Evictions)
+	}
+
+	stats := rt.ProgramStats()
+	fmt.Fprintf(w, "programs\t%v\t%v\t%v\t%v\t%v\n", stats.Len, stats.Limit, stats.Hits, stats.Misses, stats.Evictions)
 }
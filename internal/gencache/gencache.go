@@ -0,0 +1,267 @@
+// Package gencache implements the bounded caches that are used to intern
+// values and to hold the programs parsed by EVAL.
+package gencache
+
+import (
+	"sync"
+
+	"github.com/polyscone/knight/options"
+)
+
+// Key identifies an entry in a Cache that isn't keyed by a string.
+// Each cache only uses the fields that it needs and leaves the others as their
+// zero values.
+type Key struct {
+	Ints  [3]int
+	Other interface{}
+}
+
+// Stats describes the size and effectiveness of a Cache.
+type Stats struct {
+	Len       int
+	Limit     int
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// Cache is a bounded map that evicts its least recently used entries
+// generationally.
+//
+// New entries go into the current generation, and once that's full it becomes
+// the previous generation and the old previous generation is dropped.
+// Entries that are found in the previous generation are promoted back into the
+// current one, so anything that's still in use survives.
+//
+// A Cache is safe for concurrent use, except in reckless builds.
+type Cache struct {
+	mu sync.Mutex
+
+	limit    int
+	current  generation
+	previous generation
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// generation holds the entries in one generation of a Cache.
+// Entries that are keyed by strings are kept separately so that looking them
+// up can use Go's faster hashing for string keys.
+type generation struct {
+	strs map[string]interface{}
+	keys map[Key]interface{}
+}
+
+func (g *generation) len() int {
+	return len(g.strs) + len(g.keys)
+}
+
+// New returns an empty Cache with the given limit, as described by SetLimit.
+func New(limit int) *Cache {
+	var c Cache
+	c.limit = round(limit)
+
+	return &c
+}
+
+func (c *Cache) lock() {
+	if !options.Reckless {
+		c.mu.Lock()
+	}
+}
+
+func (c *Cache) unlock() {
+	if !options.Reckless {
+		c.mu.Unlock()
+	}
+}
+
+// LoadOrCreate returns the cached value for the given key, calling create to
+// make and store a new one if there isn't one.
+// The cache is locked while create is called, so it must not use the same
+// cache.
+func (c *Cache) LoadOrCreate(key Key, create func() interface{}) interface{} {
+	c.lock()
+	defer c.unlock()
+
+	if v, ok := c.loadLocked(key); ok {
+		return v
+	}
+
+	v := create()
+	if c.limit > 0 {
+		c.storeLocked(key, v)
+	}
+
+	return v
+}
+
+// LoadOrCreateString works like LoadOrCreate for caches that are keyed by
+// strings.
+func (c *Cache) LoadOrCreateString(key string, create func() interface{}) interface{} {
+	c.lock()
+	defer c.unlock()
+
+	if v, ok := c.current.strs[key]; ok {
+		c.hits++
+
+		return v
+	}
+
+	if v, ok := c.previous.strs[key]; ok {
+		c.hits++
+
+		delete(c.previous.strs, key)
+		c.storeStringLocked(key, v)
+
+		return v
+	}
+
+	c.misses++
+
+	v := create()
+	if c.limit > 0 {
+		c.storeStringLocked(key, v)
+	}
+
+	return v
+}
+
+// Load returns the cached value for the given key, if there is one.
+func (c *Cache) Load(key Key) (interface{}, bool) {
+	c.lock()
+	defer c.unlock()
+
+	return c.loadLocked(key)
+}
+
+// Store caches the given value and returns it, unless a value is already
+// cached for the same key, in which case that value is returned instead so
+// that every caller gets the same one.
+// Storing a value doesn't count as a hit or a miss.
+func (c *Cache) Store(key Key, v interface{}) interface{} {
+	c.lock()
+	defer c.unlock()
+
+	if cached, ok := c.current.keys[key]; ok {
+		return cached
+	}
+
+	if cached, ok := c.previous.keys[key]; ok {
+		return cached
+	}
+
+	if c.limit > 0 {
+		c.storeLocked(key, v)
+	}
+
+	return v
+}
+
+func (c *Cache) loadLocked(key Key) (interface{}, bool) {
+	if v, ok := c.current.keys[key]; ok {
+		c.hits++
+
+		return v, true
+	}
+
+	if v, ok := c.previous.keys[key]; ok {
+		c.hits++
+
+		delete(c.previous.keys, key)
+		c.storeLocked(key, v)
+
+		return v, true
+	}
+
+	c.misses++
+
+	return nil, false
+}
+
+func (c *Cache) storeLocked(key Key, v interface{}) {
+	c.makeRoomLocked()
+
+	if c.current.keys == nil {
+		c.current.keys = make(map[Key]interface{})
+	}
+
+	c.current.keys[key] = v
+}
+
+func (c *Cache) storeStringLocked(key string, v interface{}) {
+	c.makeRoomLocked()
+
+	if c.current.strs == nil {
+		c.current.strs = make(map[string]interface{})
+	}
+
+	c.current.strs[key] = v
+}
+
+// makeRoomLocked starts a new generation if the current one is full, which
+// holds half of the limit.
+func (c *Cache) makeRoomLocked() {
+	if c.current.len() >= c.limit/2 {
+		c.evictions += uint64(c.previous.len())
+		c.previous = c.current
+		c.current = generation{}
+	}
+}
+
+// SetLimit sets the maximum number of entries the cache can hold, evicting
+// entries straight away if it's already over the new limit.
+// Limits are rounded up to an even number, and a limit of 0 disables the cache
+// entirely.
+func (c *Cache) SetLimit(limit int) {
+	c.lock()
+	defer c.unlock()
+
+	c.limit = round(limit)
+
+	// Just drop everything rather than picking the most recently used entries
+	// out of both generations, since shrinking a cache should be rare
+	if c.current.len()+c.previous.len() > c.limit {
+		c.clearLocked()
+	}
+}
+
+// Clear evicts every entry without changing the limit or resetting the stats.
+func (c *Cache) Clear() {
+	c.lock()
+	defer c.unlock()
+
+	c.clearLocked()
+}
+
+func (c *Cache) clearLocked() {
+	c.evictions += uint64(c.current.len() + c.previous.len())
+	c.current = generation{}
+	c.previous = generation{}
+}
+
+// Stats returns the cache's current stats.
+func (c *Cache) Stats() Stats {
+	c.lock()
+	defer c.unlock()
+
+	return Stats{
+		Len:       c.current.len() + c.previous.len(),
+		Limit:     c.limit,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
+
+// round returns the given limit rounded up to an even number, since each
+// generation holds half of it, with negative limits treated as 0.
+func round(limit int) int {
+	if limit < 0 {
+		return 0
+	}
+
+	return limit + limit%2
+}
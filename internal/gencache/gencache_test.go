@@ -0,0 +1,110 @@
+package gencache_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/polyscone/knight/internal/gencache"
+)
+
+func TestCacheEviction(t *testing.T) {
+	t.Parallel()
+
+	c := gencache.New(4)
+
+	create := func(v interface{}) func() interface{} {
+		return func() interface{} { return v }
+	}
+
+	c.LoadOrCreateString("kept", create("kept"))
+	for n := 0; n < 100; n++ {
+		c.LoadOrCreate(gencache.Key{Ints: [3]int{n}}, create(n))
+
+		// Using an entry keeps it in the cache
+		if got := c.LoadOrCreateString("kept", create("replaced")); got != "kept" {
+			t.Fatalf("want %q, got %q", "kept", got)
+		}
+	}
+
+	stats := c.Stats()
+	if stats.Len > 4 {
+		t.Errorf("want at most 4 entries, got %v", stats.Len)
+	}
+
+	if stats.Hits != 100 {
+		t.Errorf("want 100 hits, got %v", stats.Hits)
+	}
+
+	if stats.Misses != 101 {
+		t.Errorf("want 101 misses, got %v", stats.Misses)
+	}
+
+	if stats.Evictions == 0 {
+		t.Error("want evictions, got none")
+	}
+}
+
+func TestCacheStore(t *testing.T) {
+	t.Parallel()
+
+	c := gencache.New(4)
+	key := gencache.Key{Other: "key"}
+
+	if got := c.Store(key, 1); got != 1 {
+		t.Errorf("want 1, got %v", got)
+	}
+
+	if got := c.Store(key, 2); got != 1 {
+		t.Errorf("want the first value to be kept, got %v", got)
+	}
+
+	if got, ok := c.Load(key); !ok || got != 1 {
+		t.Errorf("want 1, got %v (found %v)", got, ok)
+	}
+
+	if _, ok := c.Load(gencache.Key{Other: "missing"}); ok {
+		t.Error("want no value for a missing key")
+	}
+}
+
+func TestCacheLimit(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		limit int
+		want  int
+	}{
+		{-1, 0},
+		{0, 0},
+		{1, 2},
+		{3, 4},
+		{4, 4},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(strconv.Itoa(tc.limit), func(t *testing.T) {
+			t.Parallel()
+
+			c := gencache.New(16)
+			for n := 0; n < 16; n++ {
+				c.LoadOrCreateString(strconv.Itoa(n), func() interface{} { return n })
+			}
+
+			c.SetLimit(tc.limit)
+
+			for n := 0; n < 100; n++ {
+				c.LoadOrCreateString(strconv.Itoa(n), func() interface{} { return n })
+			}
+
+			stats := c.Stats()
+			if stats.Limit != tc.want {
+				t.Errorf("want limit %v, got %v", tc.want, stats.Limit)
+			}
+
+			if stats.Len > tc.want {
+				t.Errorf("want at most %v entries, got %v", tc.want, stats.Len)
+			}
+		})
+	}
+}
@@ -9,16 +9,49 @@ import (
 // Add combines the given LHS and RHS values.
 //
 // If the LHS is an integer then the RHS will be converted to an integer and
-// the return value will be the sum of the two.
+// the return value will be the sum of the two, which overflows according to
+// the interpreter's integer semantics.
 //
 // If the LHS is a string then the RHS will be converted to a string and the
 // return value will be the concatenation of the two.
+//
+// If the LHS is a list then the RHS will be converted to a list and the return
+// value will be the concatenation of the two.
 func (i *Interpreter) Add(lhs, rhs value.Value) (value.Value, error) {
 	switch lhs := lhs.(type) {
 	case *value.Int:
-		return value.NewInt(lhs.Value + rhs.AsInt().Value), nil
+		rhs, err := i.toInt(rhs)
+		if err != nil {
+			return nil, err
+		}
+
+		return i.arith(opAdd, lhs, rhs)
 	case *value.String:
-		return value.NewConcatString(lhs, rhs.AsString()), nil
+		rhs, err := i.heap.ToString(rhs)
+		if err != nil {
+			return nil, err
+		}
+
+		return i.heap.NewConcatString(lhs, rhs), nil
+	case *value.List:
+		rhs, err := i.heap.ToList(rhs)
+		if err != nil {
+			return nil, err
+		}
+
+		if lhs.Len() == 0 {
+			return rhs, nil
+		}
+
+		if rhs.Len() == 0 {
+			return lhs, nil
+		}
+
+		values := make([]value.Value, 0, lhs.Len()+rhs.Len())
+		values = append(values, lhs.Value...)
+		values = append(values, rhs.Value...)
+
+		return value.NewList(values), nil
 	default:
 		return nil, fmt.Errorf("cannot add %s and %s", lhs, rhs)
 	}
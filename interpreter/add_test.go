@@ -20,6 +20,10 @@ func TestAdd(t *testing.T) {
 		{"lhs negative int, rhs negative int", value.NewInt(-1), value.NewInt(-2), value.NewInt(-3)},
 		{"lhs positive int, rhs string", value.NewInt(25), value.NewString("10"), value.NewInt(35)},
 		{"lhs string, rhs positive int", value.NewString("foo"), value.NewInt(3), value.NewString("foo3")},
+		{"lhs list, rhs list", list(value.NewInt(1)), list(value.NewInt(2), value.NewInt(3)), list(value.NewInt(1), value.NewInt(2), value.NewInt(3))},
+		{"lhs empty list, rhs string", value.NewList(nil), value.NewString("ab"), list(value.NewString("a"), value.NewString("b"))},
+		{"lhs list, rhs int", list(value.NewString("a")), value.NewInt(-12), list(value.NewString("a"), value.NewInt(-1), value.NewInt(-2))},
+		{"lhs string, rhs list", value.NewString("foo"), list(value.NewInt(1), value.NewInt(2)), value.NewString("foo1\n2")},
 	}
 	for _, tc := range tt {
 		tc := tc
@@ -9,7 +9,12 @@ import (
 // the RHS expression if the LHS value was truthy.
 // This means that when the LHS value is false it short-circuits.
 func (i *Interpreter) And(lhs value.Value, rhs ast.Node) (value.Value, error) {
-	if !lhs.AsBool().Value {
+	b, err := value.ToBool(lhs)
+	if err != nil {
+		return nil, err
+	}
+
+	if !b.Value {
 		return lhs, nil
 	}
 
@@ -1,6 +1,7 @@
 package interpreter
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/polyscone/knight/value"
@@ -8,12 +9,28 @@ import (
 
 // ASCII returns either the string representation of an integer or the integer
 // representation of the first character of a string.
+//
+// Calling ASCII with an empty string will return an error.
+// In strict mode the integer or first character must also be one of the
+// characters that the spec requires implementations to support.
 func (i *Interpreter) ASCII(val value.Value) (value.Value, error) {
-	switch val.(type) {
+	switch val := val.(type) {
 	case *value.Int:
-		return value.NewString(string(rune(val.AsInt().Value))), nil
+		if i.strict && !portable(val.Value) {
+			return nil, undefined("ASCII code %v is not a supported character", val)
+		}
+
+		return i.heap.NewString(string(rune(val.Value))), nil
 	case *value.String:
-		return value.NewInt(int(val.AsString().Value[0])), nil
+		if val.Len() == 0 {
+			return nil, errors.New("cannot get the ascii code of an empty string")
+		}
+
+		if c := val.Value()[0]; i.strict && !portable(int(c)) {
+			return nil, undefined("ASCII character %q is not a supported character", c)
+		}
+
+		return i.heap.NewInt(int(val.Value()[0])), nil
 	}
 
 	return nil, fmt.Errorf("unknown ascii argument %v", val)
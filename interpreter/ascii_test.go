@@ -38,3 +38,26 @@ func TestASCII(t *testing.T) {
 		})
 	}
 }
+
+func TestASCIIErrors(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name  string
+		value value.Value
+	}{
+		{"empty string", value.NewString("")},
+		{"block", value.NewBlock(value.NewString("foo"))},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := interpreter.New(nil, nil).ASCII(tc.value); err == nil {
+				t.Error("want error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package interpreter
+
+import (
+	"io"
+
+	"github.com/polyscone/knight/ast"
+	"github.com/polyscone/knight/value"
+)
+
+// Backend is an execution strategy that an Interpreter can use instead of its
+// built-in tree-walk evaluator.
+//
+// The Interpreter still owns all of the runtime state, like the context, I/O,
+// step budget, and call stack, and prepares it before calling Run.
+// Backends should use the exported methods on Interpreter to access that state
+// and to implement the builtin functions, so that every backend has the same
+// semantics.
+type Backend interface {
+	Run(i *Interpreter, root ast.Node) (value.Value, error)
+}
+
+// Step counts a single step of execution against the budget set with
+// WithMaxSteps, and returns ErrBudgetExceeded once the budget has been used.
+func (i *Interpreter) Step() error {
+	if i.maxSteps <= 0 {
+		return nil
+	}
+
+	i.steps++
+
+	if i.steps > i.maxSteps {
+		return ErrBudgetExceeded
+	}
+
+	return nil
+}
+
+// Cancelled returns the context's error if the context passed to
+// ExecuteContext has been cancelled.
+func (i *Interpreter) Cancelled() error {
+	select {
+	case <-i.done:
+		return i.ctx.Err()
+	default:
+		return nil
+	}
+}
+
+// Global returns the global in the given slot of the interpreter's global
+// store, which is how compiled code refers to variables.
+func (i *Interpreter) Global(slot int) *value.Global {
+	return i.globals.Slot(slot)
+}
+
+// Compile returns the result of calling compile with the given node, which
+// backends can use for code that isn't run by EVAL, like the bodies of blocks
+// that are called after the program that created them has returned.
+//
+// The result is cached in the interpreter's runtime by the node, so it's
+// bounded and discarded in the same way as the programs parsed by EVAL, and
+// each node is only compiled once.
+// Backends that share a runtime must all compile nodes in the same way.
+func (i *Interpreter) Compile(node ast.Node, compile func(ast.Node) (interface{}, error)) (interface{}, error) {
+	return i.runtime.programs.compile(node, compile)
+}
+
+// Enter pushes a new frame onto the Knight call stack.
+// The site should be the location of the node that caused the call.
+//
+// If execution has been cancelled, or the frame would go over the limit set
+// with WithMaxDepth, then an error is returned and no frame is pushed.
+func (i *Interpreter) Enter(name string, site ast.Span) error {
+	if err := i.Cancelled(); err != nil {
+		return err
+	}
+
+	if i.maxDepth > 0 && len(i.stack) >= i.maxDepth {
+		return ErrMaxDepthExceeded
+	}
+
+	i.stack = append(i.stack, frame{name: name, site: site})
+
+	return nil
+}
+
+// Leave pops the most recent frame from the Knight call stack.
+func (i *Interpreter) Leave() {
+	i.stack = i.stack[:len(i.stack)-1]
+}
+
+// Stdin returns the reader that PROMPT should read from.
+func (i *Interpreter) Stdin() StringReader {
+	return i.stdin
+}
+
+// Stdout returns the buffered writer that OUTPUT and DUMP should write to.
+func (i *Interpreter) Stdout() io.Writer {
+	return i.stdout
+}
+
+// Flush writes any buffered output.
+// It should be called before blocking on input so that any prompt text is
+// visible.
+func (i *Interpreter) Flush() error {
+	return i.stdout.Flush()
+}
@@ -0,0 +1,71 @@
+package interpreter_test
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/lexer"
+	"github.com/polyscone/knight/parser"
+	"github.com/polyscone/knight/value"
+)
+
+// benchmarkProgram parses and executes the given source once per iteration.
+// The options are created fresh for every iteration so that any state they
+// hold, like input readers, starts from scratch.
+func benchmarkProgram(b *testing.B, source string, opts func() []interpreter.Option) {
+	b.Helper()
+
+	for n := 0; n < b.N; n++ {
+		l := lexer.New()
+		p := parser.New(l)
+		g := value.NewGlobalStore()
+		program, err := p.Parse(g, strings.NewReader(source))
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		iopts := []interpreter.Option{interpreter.WithStdout(io.Discard)}
+		if opts != nil {
+			iopts = append(iopts, opts()...)
+		}
+
+		if _, err := interpreter.New(g, p, iopts...).Execute(program); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkConcatLoop builds a long string one character at a time.
+func BenchmarkConcatLoop(b *testing.B) {
+	benchmarkProgram(b, `; = s "" ; = i 0 ; WHILE < i 20000 ; = s + s "x" = i + i 1 : LENGTH s`, nil)
+}
+
+// BenchmarkGetLoop builds a long string one character at a time and then reads
+// it back one character at a time with GET.
+func BenchmarkGetLoop(b *testing.B) {
+	benchmarkProgram(b, `; = s "" ; = i 0 ; WHILE < i 20000 ; = s + s "x" = i + i 1 ; = i 0 ; WHILE < i LENGTH s ; GET s i 1 = i + i 1 : i`, nil)
+}
+
+// BenchmarkKnightInKnight runs the Knight interpreter that's written in
+// Knight, which reads the name of the program to run from PROMPT and then
+// loads it with SYSTEM.
+func BenchmarkKnightInKnight(b *testing.B) {
+	source, err := os.ReadFile("../lexer/testdata/knight.kn")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	program := `; = s "" ; = i 0 ; WHILE < i 300 ; = s + s "ab" = i + i 1 : OUTPUT LENGTH s`
+
+	benchmarkProgram(b, string(source), func() []interpreter.Option {
+		return []interpreter.Option{
+			interpreter.WithStdin(strings.NewReader("bench.kn\n")),
+			interpreter.WithCommandRunner(&interpreter.ScriptedRunner{
+				Outputs: map[string]string{"cat bench.kn": program},
+			}),
+		}
+	})
+}
@@ -17,7 +17,7 @@ func TestBlock(t *testing.T) {
 		expr ast.Node
 	}{
 		{"wrap int", value.NewInt(1)},
-		{"wrap addition", ast.NewBinary(token.Add, value.NewInt(1), value.NewInt(2))},
+		{"wrap addition", ast.NewBinary(ast.Span{}, token.Add, value.NewInt(1), value.NewInt(2))},
 	}
 	for _, tc := range tt {
 		tc := tc
@@ -0,0 +1,8 @@
+package interpreter
+
+import "github.com/polyscone/knight/value"
+
+// Box returns a list that holds only the given value.
+func (i *Interpreter) Box(val value.Value) (value.Value, error) {
+	return value.NewList([]value.Value{val}), nil
+}
@@ -0,0 +1,44 @@
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/value"
+)
+
+// list returns a list value that holds the given values.
+func list(values ...value.Value) value.Value {
+	return value.NewList(values)
+}
+
+func TestBox(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name  string
+		value value.Value
+		want  value.Value
+	}{
+		{"int", value.NewInt(1), list(value.NewInt(1))},
+		{"string", value.NewString("foo"), list(value.NewString("foo"))},
+		{"null", value.NewNull(), list(value.NewNull())},
+		{"empty list", value.NewList(nil), list(value.NewList(nil))},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := interpreter.New(nil, nil).Box(tc.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !value.Equal(result, tc.want) {
+				t.Errorf("want %v (%p), got %v (%p)", tc.want, tc.want, result, result)
+			}
+		})
+	}
+}
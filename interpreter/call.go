@@ -9,20 +9,36 @@ import (
 
 // Call will evaluate the given value as an expression.
 // It only expects to be given a block value, but will evaluate any other
-// expression as undefined behaviour as well.
+// expression as undefined behaviour as well, unless the interpreter is in
+// strict mode.
 func (i *Interpreter) Call(arg value.Value) (value.Value, error) {
+	if err := i.checkCall(arg); err != nil {
+		return nil, err
+	}
+
 	switch v := arg.AsExpr().(type) {
 	case *value.Bool:
 		return v, nil
 	case *value.Int:
-		return v, nil
+		return i.normalise(v)
 	case *value.String:
 		return v, nil
 	case *value.Null:
 		return v, nil
+	case *value.List:
+		return v, nil
 	case ast.Node:
 		return i.eval(v)
 	}
 
 	return nil, fmt.Errorf("unknown call argument %v", arg)
 }
+
+// checkCall returns an error in strict mode if the given value isn't a block.
+func (i *Interpreter) checkCall(arg value.Value) error {
+	if _, ok := arg.(*value.Block); ok || !i.strict {
+		return nil
+	}
+
+	return undefined("CALL can only be given a block, got %s", arg)
+}
@@ -18,7 +18,7 @@ func TestCall(t *testing.T) {
 		want value.Value
 	}{
 		{"call wrapped int", value.NewInt(1), value.NewInt(1)},
-		{"call wrapped addition", ast.NewBinary(token.Add, value.NewInt(1), value.NewInt(2)), value.NewInt(3)},
+		{"call wrapped addition", ast.NewBinary(ast.Span{}, token.Add, value.NewInt(1), value.NewInt(2)), value.NewInt(3)},
 	}
 	for _, tc := range tt {
 		tc := tc
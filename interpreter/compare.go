@@ -0,0 +1,75 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/polyscone/knight/value"
+)
+
+// compare returns a negative number if the LHS value is less than the RHS
+// value, a positive number if it's greater, and zero if they're the same once
+// the RHS value has been converted to the type of the LHS value.
+func (i *Interpreter) compare(lhs, rhs value.Value) (int, error) {
+	switch lhs := lhs.(type) {
+	case *value.Bool:
+		rhs, err := value.ToBool(rhs)
+		if err != nil {
+			return 0, err
+		}
+
+		return boolToInt(lhs.Value) - boolToInt(rhs.Value), nil
+	case *value.Int:
+		rhs, err := i.toInt(rhs)
+		if err != nil {
+			return 0, err
+		}
+
+		return lhs.Cmp(rhs), nil
+	case *value.String:
+		rhs, err := i.heap.ToString(rhs)
+		if err != nil {
+			return 0, err
+		}
+
+		return strings.Compare(lhs.Value(), rhs.Value()), nil
+	case *value.List:
+		rhs, err := i.heap.ToList(rhs)
+		if err != nil {
+			return 0, err
+		}
+
+		for n, v := range lhs.Value {
+			if n >= rhs.Len() {
+				return 1, nil
+			}
+
+			c, err := i.compare(v, rhs.Value[n])
+			if err != nil {
+				return 0, err
+			}
+
+			if c != 0 {
+				return c, nil
+			}
+		}
+
+		return lhs.Len() - rhs.Len(), nil
+	}
+
+	// The spec leaves comparing anything else, like null or a block,
+	// undefined
+	if i.strict {
+		return 0, undefined("cannot compare %s and %s", lhs, rhs)
+	}
+
+	return 0, fmt.Errorf("cannot compare %s and %s", lhs, rhs)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -11,15 +11,19 @@ import (
 // LHS must be an integer, and the RHS value will be converted to an integer
 // if it isn't one already.
 //
-// Dividing by zero will return an error.
+// Dividing by zero will return an error, and the result overflows according to
+// the interpreter's integer semantics.
 func (i *Interpreter) Div(lhs, rhs value.Value) (value.Value, error) {
 	if lhs, ok := lhs.(*value.Int); ok {
-		rhs := rhs.AsInt().Value
-		if rhs == 0 {
+		rhs, err := i.toInt(rhs)
+		if err != nil {
+			return nil, err
+		}
+		if i.sign(rhs) == 0 {
 			return nil, errors.New("cannot divide by 0")
 		}
 
-		return value.NewInt(lhs.Value / rhs), nil
+		return i.arith(opDiv, lhs, rhs)
 	}
 
 	return nil, fmt.Errorf("cannot divide %s by %s", lhs, rhs)
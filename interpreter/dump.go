@@ -3,13 +3,127 @@ package interpreter
 import (
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/polyscone/knight/value"
 )
 
-// Dump will print the debug/test string output for a given value.
+// DumpFormat selects how DUMP prints values.
+type DumpFormat int
+
+// Dump formats.
+const (
+	// DumpLegacy prints the debug form of each value's Dump method, like
+	// Number(5), String(foo), and Null(), and DUMP returns null.
+	DumpLegacy DumpFormat = iota
+
+	// DumpSpec prints values in the format described by the Knight spec, which
+	// looks like Knight source code with escaped strings, like 5, "foo", null,
+	// and [1, "a"], and DUMP returns its argument.
+	DumpSpec
+)
+
+// String returns the name of the format.
+func (f DumpFormat) String() string {
+	switch f {
+	case DumpLegacy:
+		return "legacy"
+	case DumpSpec:
+		return "spec"
+	}
+
+	return fmt.Sprintf("DumpFormat(%d)", int(f))
+}
+
+// ParseDumpFormat returns the format with the given name, as returned by
+// DumpFormat.String.
+func ParseDumpFormat(name string) (DumpFormat, error) {
+	for _, f := range []DumpFormat{DumpLegacy, DumpSpec} {
+		if f.String() == name {
+			return f, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown dump format %q", name)
+}
+
+// Dump will print the given value in the interpreter's dump format.
+// In strict mode the value can't be or contain a block.
 func (i *Interpreter) Dump(w io.Writer, val value.Value) (value.Value, error) {
+	if err := i.checkNoBlocks("DUMP", val); err != nil {
+		return nil, err
+	}
+
+	if i.dump == DumpSpec {
+		var sb strings.Builder
+		if err := i.dumpSpec(&sb, val); err != nil {
+			return nil, err
+		}
+
+		fmt.Fprint(w, sb.String())
+
+		return val, nil
+	}
+
 	fmt.Fprint(w, val.Dump())
 
 	return value.NewNull(), nil
 }
+
+// dumpSpec writes the given value to the builder in the DumpSpec format.
+// Blocks can't be printed in this format, so they result in an error.
+func (i *Interpreter) dumpSpec(sb *strings.Builder, val value.Value) error {
+	switch val := val.(type) {
+	case *value.Bool:
+		fmt.Fprint(sb, val.Value)
+	case *value.Int:
+		s, err := i.heap.ToString(val)
+		if err != nil {
+			return err
+		}
+
+		sb.WriteString(s.Value())
+	case *value.String:
+		sb.WriteByte('"')
+
+		s := val.Value()
+		for n := 0; n < len(s); n++ {
+			switch c := s[n]; c {
+			case '\\':
+				sb.WriteString(`\\`)
+			case '"':
+				sb.WriteString(`\"`)
+			case '\n':
+				sb.WriteString(`\n`)
+			case '\r':
+				sb.WriteString(`\r`)
+			case '\t':
+				sb.WriteString(`\t`)
+			default:
+				sb.WriteByte(c)
+			}
+		}
+
+		sb.WriteByte('"')
+	case *value.Null:
+		sb.WriteString("null")
+	case *value.List:
+		sb.WriteByte('[')
+
+		for n, v := range val.Value {
+			if n > 0 {
+				sb.WriteString(", ")
+			}
+
+			if err := i.dumpSpec(sb, v); err != nil {
+				return err
+			}
+		}
+
+		sb.WriteByte(']')
+	default:
+		return fmt.Errorf("cannot dump %s", val)
+	}
+
+	return nil
+}
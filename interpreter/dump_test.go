@@ -40,3 +40,98 @@ func TestDump(t *testing.T) {
 		})
 	}
 }
+
+func TestDumpSpec(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name  string
+		value value.Value
+		want  string
+	}{
+		{"bool true", value.NewBool(true), "true"},
+		{"bool false", value.NewBool(false), "false"},
+		{"null", value.NewNull(), "null"},
+		{"int 123", value.NewInt(123), "123"},
+		{"negative int", value.NewInt(-45), "-45"},
+		{"big int", value.NewBigInt(value.AtoiBig("123456789012345678901234567890")), "123456789012345678901234567890"},
+		{`string "foo"`, value.NewString("foo"), `"foo"`},
+		{"empty string", value.NewString(""), `""`},
+		{"string with escapes", value.NewString("a\\b\"c\nd\re\tf"), `"a\\b\"c\nd\re\tf"`},
+		{"string with single quotes", value.NewString("it's"), `"it's"`},
+		{"empty list", list(), "[]"},
+		{"list", list(value.NewInt(1), value.NewString("a"), value.NewNull()), `[1, "a", null]`},
+		{"nested list", list(list(value.NewBool(true)), list()), "[[true], []]"},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			i := interpreter.New(nil, nil, interpreter.WithDumpFormat(interpreter.DumpSpec))
+			got, err := i.Dump(&buf, tc.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Spec conformant DUMP returns its argument
+			if !value.Equal(got, tc.value) {
+				t.Errorf("want %v (%p), got %v (%p)", tc.value, tc.value, got, got)
+			}
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDumpSpecErrors(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name  string
+		value value.Value
+	}{
+		{"block", value.NewBlock(value.NewInt(1))},
+		{"list with block", list(value.NewInt(1), value.NewBlock(value.NewInt(1)))},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			i := interpreter.New(nil, nil, interpreter.WithDumpFormat(interpreter.DumpSpec))
+			if _, err := i.Dump(&buf, tc.value); err == nil {
+				t.Error("want error, got nil")
+			}
+
+			if got := buf.String(); got != "" {
+				t.Errorf("want no output, got %q", got)
+			}
+		})
+	}
+}
+
+func TestParseDumpFormat(t *testing.T) {
+	t.Parallel()
+
+	for _, want := range []interpreter.DumpFormat{interpreter.DumpLegacy, interpreter.DumpSpec} {
+		got, err := interpreter.ParseDumpFormat(want.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	}
+
+	if _, err := interpreter.ParseDumpFormat("unknown"); err == nil {
+		t.Error("want error, got nil")
+	}
+}
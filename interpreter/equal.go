@@ -7,6 +7,15 @@ import "github.com/polyscone/knight/value"
 //
 // For LHS and RHS to be considered equal they must both be of the same
 // value and type.
+// In strict mode neither value can be or contain a block.
 func (i *Interpreter) Equal(lhs, rhs value.Value) (value.Value, error) {
+	if err := i.checkNoBlocks("?", lhs); err != nil {
+		return nil, err
+	}
+
+	if err := i.checkNoBlocks("?", rhs); err != nil {
+		return nil, err
+	}
+
 	return value.NewBool(value.Equal(lhs, rhs)), nil
 }
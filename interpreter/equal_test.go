@@ -22,6 +22,10 @@ func TestEqual(t *testing.T) {
 		{"1 does not equal 2", value.NewInt(1), value.NewInt(2), false},
 		{"foo equals foo", value.NewString("foo"), value.NewString("foo"), true},
 		{"foo does not equal bar", value.NewString("foo"), value.NewString("bar"), false},
+		{"empty list equals empty list", value.NewList(nil), value.NewList(nil), true},
+		{"list equals list with equal elements", list(value.NewInt(1), list(value.NewString("a"))), list(value.NewInt(1), list(value.NewString("a"))), true},
+		{"list does not equal longer list", list(value.NewInt(1)), list(value.NewInt(1), value.NewInt(1)), false},
+		{"list does not equal its string conversion", list(value.NewString("a")), value.NewString("a"), false},
 	}
 	for _, tc := range tt {
 		tc := tc
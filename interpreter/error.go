@@ -0,0 +1,139 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/polyscone/knight/ast"
+)
+
+// ErrBudgetExceeded is returned when a program evaluates more nodes than the
+// limit set with WithMaxSteps.
+var ErrBudgetExceeded = errors.New("execution step budget exceeded")
+
+// ErrMaxDepthExceeded is returned when a program makes more nested calls than
+// the limit set with WithMaxDepth.
+var ErrMaxDepthExceeded = errors.New("maximum call depth exceeded")
+
+// RuntimeError describes an error that occurred while evaluating a program
+// along with the region of source code that was being evaluated, and the
+// Knight call stack at the time.
+type RuntimeError struct {
+	Span  ast.Span
+	Err   error
+	Stack []Frame
+}
+
+// Frame describes one level of the Knight call stack.
+type Frame struct {
+	// Name is the name of the global whose block was called.
+	// Frames that don't come from a global are named "<block>" for blocks
+	// passed directly to CALL, "<eval>" for code run by EVAL, and "<program>"
+	// for the top level of the program.
+	Name string
+
+	// Span is the location that was being evaluated in the frame when the
+	// error occurred.
+	Span ast.Span
+}
+
+// Error returns the error message prefixed with its location.
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Span, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// Location returns the region of source code that caused the error.
+func (e *RuntimeError) Location() ast.Span {
+	return e.Span
+}
+
+// located is implemented by errors that carry their own location, like
+// RuntimeError and the parser's errors from EVAL.
+type located interface {
+	Location() ast.Span
+}
+
+// maxTraceFrames is the maximum number of frames that StackTrace prints.
+const maxTraceFrames = 100
+
+// StackTrace returns the Knight call stack formatted in the same way as a Go
+// panic trace, with the innermost frame first.
+// Very deep stacks only have their innermost and outermost frames printed.
+func (e *RuntimeError) StackTrace() string {
+	var sb strings.Builder
+
+	for n, f := range e.Stack {
+		if len(e.Stack) > maxTraceFrames && n == maxTraceFrames/2 {
+			fmt.Fprintf(&sb, "...%v frames elided...\n", len(e.Stack)-maxTraceFrames)
+		}
+
+		if len(e.Stack) > maxTraceFrames && n >= maxTraceFrames/2 && n < len(e.Stack)-maxTraceFrames/2 {
+			continue
+		}
+
+		fmt.Fprintf(&sb, "%v\n\t%v\n", f.Name, f.Span)
+	}
+
+	return sb.String()
+}
+
+// locate wraps the given error in a RuntimeError using the span of the given
+// node, unless the node has no span.
+// Since nodes are evaluated from the inside out this means the reported
+// location is always the innermost node that failed.
+func (i *Interpreter) locate(node ast.Node, err error) error {
+	span, ok := ast.Locate(node)
+	if !ok {
+		return err
+	}
+
+	return i.Locate(span, err)
+}
+
+// Locate wraps the given error in a RuntimeError using the given span and a
+// snapshot of the current call stack, unless it already has a location, or
+// the span is empty.
+func (i *Interpreter) Locate(span ast.Span, err error) error {
+	if span.IsZero() {
+		return err
+	}
+
+	var lerr located
+	if errors.As(err, &lerr) {
+		return err
+	}
+
+	// Exiting isn't really an error so it shouldn't have a location either
+	var exit *ExitError
+	if errors.As(err, &exit) {
+		return err
+	}
+
+	// Each frame only knows where it was called from, which is a location in
+	// its caller, so the innermost frame uses the error's location and every
+	// other frame uses the call site of the frame above it
+	stack := make([]Frame, len(i.stack)+1)
+	for n := range stack {
+		depth := len(i.stack) - n
+
+		name := "<program>"
+		if depth > 0 {
+			name = i.stack[depth-1].name
+		}
+
+		site := span
+		if n > 0 {
+			site = i.stack[depth].site
+		}
+
+		stack[n] = Frame{Name: name, Span: site}
+	}
+
+	return &RuntimeError{Span: span, Err: err, Stack: stack}
+}
@@ -0,0 +1,214 @@
+package interpreter_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/lexer"
+	"github.com/polyscone/knight/parser"
+	"github.com/polyscone/knight/value"
+)
+
+func TestRuntimeErrorLocation(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"undefined variable", "; = x 1\n: + x y", `test.kn:2:7: attempted to access undefined variable "y"`},
+		{"divide by zero", "+ 1 / 2 0", "test.kn:1:5: cannot divide by 0"},
+		{"inside called block", "; = f BLOCK\n  % 1 0\n: CALL f", "test.kn:2:3: cannot modulo by 0"},
+		{"inside eval", `EVAL "+ 1 a"`, `<eval>:1:5: attempted to access undefined variable "a"`},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := lexer.New()
+			p := parser.New(l)
+			g := value.NewGlobalStore()
+			program, err := p.ParseFile(g, "test.kn", strings.NewReader(tc.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = interpreter.New(g, p).Execute(program)
+
+			var rerr *interpreter.RuntimeError
+			if !errors.As(err, &rerr) {
+				t.Fatalf("want *interpreter.RuntimeError, got %#v", err)
+			}
+
+			if got := err.Error(); got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestEvalParseErrorLocation(t *testing.T) {
+	t.Parallel()
+
+	l := lexer.New()
+	p := parser.New(l)
+	g := value.NewGlobalStore()
+	program, err := p.ParseFile(g, "test.kn", strings.NewReader(`; = a "+ 1" : EVAL a`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = interpreter.New(g, p).Execute(program)
+
+	// Parse errors already have a location in the EVAL program, so they
+	// shouldn't be given another one in the program that called EVAL
+	var perr *parser.Error
+	if !errors.As(err, &perr) {
+		t.Fatalf("want *parser.Error, got %#v", err)
+	}
+
+	if want, got := "<eval>:1:4: unexpected end of input", err.Error(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestBlockConversionErrors(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		source string
+	}{
+		{"not", "! BLOCK 1"},
+		{"add", "+ 1 BLOCK 1"},
+		{"concat", `+ "foo" BLOCK 1`},
+		{"if", "IF BLOCK 1 1 2"},
+		{"while", "WHILE BLOCK 1 1"},
+		{"length", "LENGTH BLOCK 1"},
+		{"output", "OUTPUT BLOCK 1"},
+		{"less", "< 1 BLOCK 1"},
+		{"and", "& BLOCK 1 1"},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := lexer.New()
+			p := parser.New(l)
+			g := value.NewGlobalStore()
+			program, err := p.Parse(g, strings.NewReader(tc.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = interpreter.New(g, p).Execute(program)
+
+			var cerr *value.ConversionError
+			if !errors.As(err, &cerr) {
+				t.Errorf("want *value.ConversionError, got %#v", err)
+			}
+		})
+	}
+}
+
+func TestRuntimeErrorStack(t *testing.T) {
+	t.Parallel()
+
+	const source = `; = f BLOCK
+  / 1 0
+; = g BLOCK
+  + 1 CALL f
+: OUTPUT CALL g`
+
+	l := lexer.New()
+	p := parser.New(l)
+	g := value.NewGlobalStore()
+	program, err := p.ParseFile(g, "test.kn", strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = interpreter.New(g, p).Execute(program)
+
+	var rerr *interpreter.RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("want *interpreter.RuntimeError, got %#v", err)
+	}
+
+	want := "f\n\ttest.kn:2:3\ng\n\ttest.kn:4:12\n<program>\n\ttest.kn:5:15\n"
+	if got := rerr.StackTrace(); got != want {
+		t.Errorf("\nwant:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestRuntimeErrorTailCallStack(t *testing.T) {
+	t.Parallel()
+
+	const source = `; = f BLOCK
+  / 1 0
+; = g BLOCK
+  ; 1 CALL f
+: OUTPUT CALL g`
+
+	l := lexer.New()
+	p := parser.New(l)
+	g := value.NewGlobalStore()
+	program, err := p.ParseFile(g, "test.kn", strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = interpreter.New(g, p).Execute(program)
+
+	var rerr *interpreter.RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("want *interpreter.RuntimeError, got %#v", err)
+	}
+
+	// The call to f is in tail position, so g's frame is replaced
+	want := "f\n\ttest.kn:2:3\n<program>\n\ttest.kn:4:12\n"
+	if got := rerr.StackTrace(); got != want {
+		t.Errorf("\nwant:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestRuntimeErrorStackElided(t *testing.T) {
+	t.Parallel()
+
+	const source = `; = f BLOCK + 1 CALL f : CALL f`
+
+	l := lexer.New()
+	p := parser.New(l)
+	g := value.NewGlobalStore()
+	program, err := p.ParseFile(g, "test.kn", strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = interpreter.New(g, p, interpreter.WithMaxDepth(200)).Execute(program)
+
+	var rerr *interpreter.RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("want *interpreter.RuntimeError, got %#v", err)
+	}
+
+	if want, got := 201, len(rerr.Stack); want != got {
+		t.Errorf("want %v frames, got %v", want, got)
+	}
+
+	trace := rerr.StackTrace()
+	if want := "\n...101 frames elided...\n"; !strings.Contains(trace, want) {
+		t.Errorf("want trace to contain %q, got:\n%s", want, trace)
+	}
+
+	if want, got := 100, strings.Count(trace, "\n\t"); want != got {
+		t.Errorf("want %v frames printed, got %v", want, got)
+	}
+}
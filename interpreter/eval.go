@@ -2,44 +2,71 @@ package interpreter
 
 import (
 	"strings"
-	"sync"
 
 	"github.com/polyscone/knight/ast"
-	"github.com/polyscone/knight/options"
 	"github.com/polyscone/knight/value"
 )
 
-var programs = struct {
-	sync.Mutex
-	data map[*value.String]ast.Program
-}{data: make(map[*value.String]ast.Program)}
-
 // Eval will execute the program in the given value.
 func (i *Interpreter) Eval(val value.Value) (value.Value, error) {
-	s := val.AsString()
+	program, err := i.ParseEval(val)
+	if err != nil {
+		return nil, err
+	}
 
-	if !options.Reckless {
-		programs.Lock()
+	return i.eval(program.Root)
+}
+
+// ParseEval parses the string conversion of the given value as a program for
+// EVAL, using the interpreter's parser and globals.
+// Parsed programs are cached in the interpreter's runtime by their source code,
+// so calling this more than once with the same string will usually return the
+// same program.
+func (i *Interpreter) ParseEval(val value.Value) (ast.Program, error) {
+	entry, err := i.parseEval(val)
+	if err != nil {
+		return ast.Program{}, err
 	}
 
-	if program, ok := programs.data[s]; ok {
-		if !options.Reckless {
-			programs.Unlock()
-		}
+	return entry.program, nil
+}
 
-		return i.eval(program.Root)
+// CompileEval parses the given value as a program for EVAL in the same way as
+// ParseEval, and returns the result of calling compile with it.
+//
+// The result is cached in the interpreter's runtime along with the program, so
+// backends can use this to only compile each program once, and anything they
+// compile is discarded along with the program.
+// Backends that share a runtime must all compile programs in the same way.
+func (i *Interpreter) CompileEval(val value.Value, compile func(ast.Program) (interface{}, error)) (interface{}, error) {
+	entry, err := i.parseEval(val)
+	if err != nil {
+		return nil, err
 	}
 
-	program, err := i.parser.Parse(i.globals, strings.NewReader(s.Value))
+	return entry.code.get(func() (interface{}, error) {
+		return compile(entry.program)
+	})
+}
+
+func (i *Interpreter) parseEval(val value.Value) (*evalProgram, error) {
+	s, err := i.heap.ToString(val)
 	if err != nil {
 		return nil, err
 	}
 
-	programs.data[s] = program
+	programs := &i.runtime.programs
+	key := programKey{globals: i.globals, source: s.Value()}
+	if entry, ok := programs.load(key); ok {
+		return entry, nil
+	}
 
-	if !options.Reckless {
-		programs.Unlock()
+	program, err := i.parser.ParseFile(i.globals, "<eval>", strings.NewReader(key.source))
+	if err != nil {
+		programs.fail()
+
+		return nil, err
 	}
 
-	return i.eval(program.Root)
+	return programs.store(key, program), nil
 }
@@ -1,6 +1,7 @@
 package interpreter_test
 
 import (
+	"strconv"
 	"testing"
 
 	"github.com/polyscone/knight/interpreter"
@@ -34,9 +35,115 @@ func TestEval(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			if result != tc.want {
+			if !value.Equal(result, tc.want) {
 				t.Errorf("want %v, got %v", tc.want, result)
 			}
 		})
 	}
 }
+
+func TestEvalParseError(t *testing.T) {
+	t.Parallel()
+
+	l := lexer.New()
+	p := parser.New(l)
+	g := value.NewGlobalStore()
+	i := interpreter.New(g, p)
+
+	// A failed parse used to leave the program cache locked, so every EVAL
+	// after it would deadlock
+	for n := 0; n < 3; n++ {
+		if _, err := i.Eval(value.NewString(`+ 1`)); err == nil {
+			t.Fatal("want an error for invalid code, got nil")
+		}
+
+		result, err := i.Eval(value.NewString(`+ 1 2`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := value.NewInt(3); !value.Equal(result, want) {
+			t.Errorf("want %v, got %v", want, result)
+		}
+	}
+
+	stats := i.Runtime().ProgramStats()
+	if want, got := uint64(3), stats.Errors; want != got {
+		t.Errorf("want %v errors, got %v", want, got)
+	}
+
+	// Invalid code isn't cached, so it's parsed again every time
+	if want, got := uint64(4), stats.Misses; want != got {
+		t.Errorf("want %v misses, got %v", want, got)
+	}
+
+	if want, got := uint64(2), stats.Hits; want != got {
+		t.Errorf("want %v hits, got %v", want, got)
+	}
+}
+
+func TestEvalCache(t *testing.T) {
+	t.Parallel()
+
+	l := lexer.New()
+	p := parser.New(l)
+	g := value.NewGlobalStore()
+	i := interpreter.New(g, p)
+
+	// Programs are cached by their contents rather than by the string value
+	a, err := i.ParseEval(value.NewUniqueString("+ a 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := i.ParseEval(value.NewUniqueString("+ a 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Root != b.Root {
+		t.Errorf("want the same program for the same source, got %v and %v", a.Root, b.Root)
+	}
+
+	rt := i.Runtime()
+	rt.SetProgramCacheLimit(4)
+
+	for n := 0; n < 100; n++ {
+		if _, err := i.Eval(value.NewString("+ 1 " + strconv.Itoa(n))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := rt.ProgramStats()
+	if want, got := 4, stats.Limit; want != got {
+		t.Errorf("want limit %v, got %v", want, got)
+	}
+
+	if stats.Len > 4 {
+		t.Errorf("want at most 4 programs, got %v", stats.Len)
+	}
+
+	if stats.Evictions == 0 {
+		t.Error("want evictions, got none")
+	}
+
+	rt.SetProgramCacheLimit(0)
+
+	c, err := i.ParseEval(value.NewString("+ a 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Root == a.Root {
+		t.Error("want a new program when the cache is disabled")
+	}
+
+	if got := rt.ProgramStats().Len; got != 0 {
+		t.Errorf("want an empty cache, got %v programs", got)
+	}
+	rt.SetProgramCacheLimit(3)
+
+	if want, got := 4, rt.ProgramStats().Limit; want != got {
+		t.Errorf("want odd limit to be rounded up to %v, got %v", want, got)
+	}
+}
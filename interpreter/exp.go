@@ -2,7 +2,6 @@ package interpreter
 
 import (
 	"fmt"
-	"math"
 
 	"github.com/polyscone/knight/value"
 )
@@ -11,15 +10,37 @@ import (
 // The LHS value must be an integer, and the RHS will be converted to an
 // integer if it isn't one already.
 //
-// If LHS is zero then RHS must be positive.
+// If LHS is zero then RHS must be positive, and in strict mode RHS can't be
+// negative at all.
+// The result is calculated exactly by repeated squaring and overflows according
+// to the interpreter's integer semantics, and negative powers truncate towards
+// zero.
+//
+// If the LHS value is a list then the result is a string that joins the string
+// conversions of its values with the string conversion of the RHS value.
 func (i *Interpreter) Exp(lhs, rhs value.Value) (value.Value, error) {
+	if lhs, ok := lhs.(*value.List); ok {
+		sep, err := i.heap.ToString(rhs)
+		if err != nil {
+			return nil, err
+		}
+
+		return i.heap.Join(lhs, sep.Value())
+	}
+
 	if lhs, ok := lhs.(*value.Int); ok {
-		rhs := rhs.AsInt().Value
-		if lhs.Value == 0 && rhs < 0 {
+		rhs, err := i.toInt(rhs)
+		if err != nil {
+			return nil, err
+		}
+		if i.sign(lhs) == 0 && i.sign(rhs) < 0 {
 			return nil, fmt.Errorf("cannot raise %v to a negative power", lhs)
 		}
+		if i.strict && i.sign(rhs) < 0 {
+			return nil, undefined("cannot raise %v to negative power %v", lhs, rhs)
+		}
 
-		return value.NewInt(int(math.Pow(float64(lhs.Value), float64(rhs)))), nil
+		return i.arith(opExp, lhs, rhs)
 	}
 
 	return nil, fmt.Errorf("cannot raise %s to %s", lhs, rhs)
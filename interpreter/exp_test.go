@@ -20,6 +20,9 @@ func TestExp(t *testing.T) {
 		{"lhs positive int, rhs one", value.NewInt(10), value.NewInt(1), value.NewInt(10)},
 		{"lhs positive int, rhs two", value.NewInt(10), value.NewInt(2), value.NewInt(100)},
 		{"lhs negative int, rhs two", value.NewInt(-10), value.NewInt(2), value.NewInt(100)},
+		{"lhs list, rhs string", list(value.NewInt(1), value.NewString("a"), value.NewBool(true)), value.NewString(", "), value.NewString("1, a, true")},
+		{"lhs nested list, rhs empty string", list(value.NewInt(1), list(value.NewInt(2), value.NewInt(3))), value.NewString(""), value.NewString("12\n3")},
+		{"lhs empty list, rhs string", value.NewList(nil), value.NewString("-"), value.NewString("")},
 	}
 	for _, tc := range tt {
 		tc := tc
@@ -1,22 +1,86 @@
 package interpreter
 
-import "github.com/polyscone/knight/value"
+import (
+	"fmt"
 
-// Get returns a substring of the given string value.
+	"github.com/polyscone/knight/value"
+)
+
+// Get returns a substring of the given string value, or a sublist if the value
+// is a list.
+//
+// The start and count values must describe a range that is within the bounds
+// of the string or list, otherwise an error is returned.
+// In strict mode the value must already be a string or a list.
 func (i *Interpreter) Get(strVal, startVal, countVal value.Value) (value.Value, error) {
-	start := startVal.AsInt().Value
-	count := countVal.AsInt().Value
+	if err := i.checkSequence("GET", strVal); err != nil {
+		return nil, err
+	}
+
+	if list, ok := strVal.(*value.List); ok {
+		start, count, err := i.bounds("list", list.Len(), startVal, countVal)
+		if err != nil {
+			return nil, err
+		}
+
+		return value.NewList(list.Value[start : start+count]), nil
+	}
+
+	str, start, count, err := i.rangeArgs(strVal, startVal, countVal)
+	if err != nil {
+		return nil, err
+	}
+
 	amount := start + count
 
 	if amount == 0 {
-		return value.NewString(""), nil
+		return i.heap.NewString(""), nil
+	}
+
+	if start == 0 && amount == str.Len() {
+		return str, nil
 	}
 
-	str := strVal.AsString()
+	return i.heap.NewSubString(str, start, amount), nil
+}
+
+// rangeArgs converts the arguments shared by GET and SUBSTITUTE and checks that
+// the range they describe fits within the string.
+func (i *Interpreter) rangeArgs(strVal, startVal, countVal value.Value) (*value.String, int, int, error) {
+	str, err := i.heap.ToString(strVal)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	start, count, err := i.bounds("string", str.Len(), startVal, countVal)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	return str, start, count, nil
+}
+
+// bounds converts the start and count arguments shared by GET and SUBSTITUTE
+// and checks that the range they describe fits within a string or list of the
+// given length.
+func (i *Interpreter) bounds(kind string, length int, startVal, countVal value.Value) (int, int, error) {
+	start, err := i.toInt(startVal)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	count, err := i.toInt(countVal)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if start.Value < 0 || start.Value > length {
+		return 0, 0, fmt.Errorf("start index %v is out of range for a %v of length %v", start, kind, length)
+	}
 
-	if start == 0 && amount == len(str.Value) {
-		return strVal, nil
+	if count.Value < 0 || count.Value > length-start.Value {
+		return 0, 0, fmt.Errorf("count %v from index %v is out of range for a %v of length %v", count, start, kind, length)
 	}
 
-	return value.NewString(str.Value[start:amount]), nil
+	return start.Value, count.Value, nil
 }
@@ -42,3 +42,74 @@ func TestGet(t *testing.T) {
 		})
 	}
 }
+
+func TestGetErrors(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name  string
+		str   string
+		start int
+		count int
+	}{
+		{"negative start", "foo", -1, 1},
+		{"negative count", "foo", 1, -1},
+		{"start past the end", "foo", 4, 0},
+		{"count past the end", "foo", 1, 3},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			str := value.NewString(tc.str)
+			start := value.NewInt(tc.start)
+			count := value.NewInt(tc.count)
+			if _, err := interpreter.New(nil, nil).Get(str, start, count); err == nil {
+				t.Error("want error, got nil")
+			}
+		})
+	}
+}
+
+func TestGetList(t *testing.T) {
+	t.Parallel()
+
+	abc := list(value.NewString("a"), value.NewString("b"), value.NewString("c"))
+
+	tt := []struct {
+		name  string
+		start int
+		count int
+		want  value.Value
+	}{
+		{"start and count zero", 0, 0, value.NewList(nil)},
+		{"start at the end and count zero", 3, 0, value.NewList(nil)},
+		{"whole list", 0, 3, abc},
+		{"get middle value", 1, 1, list(value.NewString("b"))},
+		{"get last values", 1, 2, list(value.NewString("b"), value.NewString("c"))},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			start := value.NewInt(tc.start)
+			count := value.NewInt(tc.count)
+			result, err := interpreter.New(nil, nil).Get(abc, start, count)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !value.Equal(result, tc.want) {
+				t.Errorf("want %v (%p), got %v (%p)", tc.want, tc.want, result, result)
+			}
+		})
+	}
+
+	if _, err := interpreter.New(nil, nil).Get(abc, value.NewInt(2), value.NewInt(2)); err == nil {
+		t.Error("want error for a range past the end, got nil")
+	}
+}
@@ -22,6 +22,10 @@ func TestGreater(t *testing.T) {
 		{"1 is not greater than 2", value.NewInt(1), value.NewInt(2), false},
 		{"b is greater than a", value.NewString("b"), value.NewString("a"), true},
 		{"a is not greater than b", value.NewString("a"), value.NewString("b"), false},
+		{"list is greater than list with lesser element", list(value.NewInt(1), value.NewInt(3)), list(value.NewInt(1), value.NewInt(2)), true},
+		{"list is greater than its prefix", list(value.NewInt(1), value.NewInt(2)), list(value.NewInt(1)), true},
+		{"list is not greater than itself", list(value.NewInt(1)), list(value.NewInt(1)), false},
+		{"nested lists are compared", list(list(value.NewString("b"))), list(list(value.NewString("a"), value.NewString("c"))), true},
 	}
 	for _, tc := range tt {
 		tc := tc
@@ -0,0 +1,29 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/polyscone/knight/value"
+)
+
+// Head returns the first value in the given list, or the first byte of the
+// given string.
+// Empty lists and strings don't have a head, so they result in an error.
+func (i *Interpreter) Head(val value.Value) (value.Value, error) {
+	switch val := val.(type) {
+	case *value.List:
+		if val.Len() == 0 {
+			return nil, fmt.Errorf("cannot take the head of an empty list")
+		}
+
+		return val.Value[0], nil
+	case *value.String:
+		if val.Len() == 0 {
+			return nil, fmt.Errorf("cannot take the head of an empty string")
+		}
+
+		return i.heap.NewSubString(val, 0, 1), nil
+	}
+
+	return nil, fmt.Errorf("cannot take the head of %s", val)
+}
@@ -9,7 +9,12 @@ import (
 // the condition value is true, otherwise it will evaluate and return the
 // alternative value.
 func (i *Interpreter) If(condition value.Value, consequence, alternative ast.Node) (value.Value, error) {
-	if condition.AsBool().Value {
+	b, err := value.ToBool(condition)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.Value {
 		return i.eval(consequence)
 	}
 
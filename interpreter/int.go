@@ -0,0 +1,388 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/polyscone/knight/value"
+)
+
+// ErrIntOverflow is wrapped by the errors that are returned when an integer
+// operation overflows with IntChecked64 semantics.
+var ErrIntOverflow = errors.New("integer overflow")
+
+// IntSemantics selects how integer arithmetic behaves when a result doesn't fit
+// in an integer.
+type IntSemantics int
+
+// Integer semantics.
+const (
+	// IntNative uses Go's int, which silently wraps at the platform's word
+	// size.
+	IntNative IntSemantics = iota
+
+	// IntWrap32 wraps every integer to 32 bits, which is the smallest range
+	// that the spec requires, and matches implementations that use 32-bit
+	// integers.
+	IntWrap32
+
+	// IntChecked64 uses 64-bit integers and returns an error that wraps
+	// ErrIntOverflow instead of wrapping.
+	IntChecked64
+
+	// IntBig never overflows, and uses arbitrary-precision integers for any
+	// results that don't fit in 64 bits.
+	IntBig
+)
+
+// String returns the name of the semantics.
+func (s IntSemantics) String() string {
+	switch s {
+	case IntNative:
+		return "native"
+	case IntWrap32:
+		return "wrap32"
+	case IntChecked64:
+		return "checked64"
+	case IntBig:
+		return "big"
+	}
+
+	return fmt.Sprintf("IntSemantics(%d)", int(s))
+}
+
+// ParseIntSemantics returns the semantics with the given name, as returned by
+// IntSemantics.String.
+func ParseIntSemantics(name string) (IntSemantics, error) {
+	for _, s := range []IntSemantics{IntNative, IntWrap32, IntChecked64, IntBig} {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown integer semantics %q", name)
+}
+
+// maxBigBits is the largest number of bits that raising an integer to a power
+// is allowed to produce with IntBig semantics, so that a single ^ can't
+// exhaust memory.
+const maxBigBits = 1 << 24
+
+// intOp is an integer operation that can be applied with any semantics.
+type intOp struct {
+	name string
+
+	// small applies the operation to 64-bit integers and reports whether the
+	// wrapped result it returns is exact.
+	small func(a, b int64) (int64, bool)
+
+	// big applies the operation exactly.
+	// The operands must not be modified.
+	big func(a, b *big.Int) (*big.Int, error)
+}
+
+// Integer operations.
+var (
+	opAdd = intOp{"+", add64, func(a, b *big.Int) (*big.Int, error) { return new(big.Int).Add(a, b), nil }}
+	opSub = intOp{"-", sub64, func(a, b *big.Int) (*big.Int, error) { return new(big.Int).Sub(a, b), nil }}
+	opMul = intOp{"*", mul64, func(a, b *big.Int) (*big.Int, error) { return new(big.Int).Mul(a, b), nil }}
+	opDiv = intOp{"/", div64, func(a, b *big.Int) (*big.Int, error) { return new(big.Int).Quo(a, b), nil }}
+	opMod = intOp{"%", mod64, func(a, b *big.Int) (*big.Int, error) { return new(big.Int).Rem(a, b), nil }}
+	opExp = intOp{"^", pow64, powBig}
+)
+
+// arith applies the given integer operation to the given operands using the
+// interpreter's integer semantics.
+//
+// In strict mode the operands and the exact result must also fit in 32 bits.
+func (i *Interpreter) arith(op intOp, lhs, rhs *value.Int) (*value.Int, error) {
+	if i.ints == IntBig {
+		return i.arithBig(op, lhs, rhs)
+	}
+
+	a, aExact := i.operand(lhs)
+	b, bExact := i.operand(rhs)
+	result, exact := op.small(a, b)
+	exact = exact && aExact && bExact
+
+	if i.strict && (!exact || !fits32(lhs) || !fits32(rhs) || result < math.MinInt32 || result > math.MaxInt32) {
+		return nil, undefined("%v %v %v overflows the 32-bit range", lhs, op.name, rhs)
+	}
+
+	switch i.ints {
+	case IntWrap32:
+		// Wrapping at 64 bits and then at 32 bits is the same as wrapping
+		// the exact result at 32 bits
+		return i.heap.NewInt(int(int32(result))), nil
+	case IntChecked64:
+		if !exact {
+			return nil, fmt.Errorf("%w: %v %v %v", ErrIntOverflow, lhs, op.name, rhs)
+		}
+	}
+
+	return i.newInt64(result), nil
+}
+
+// arithBig implements arith for IntBig semantics.
+// Operations on small integers are done with 64-bit integers, and only fall
+// back to big integers when the result overflows.
+func (i *Interpreter) arithBig(op intOp, lhs, rhs *value.Int) (*value.Int, error) {
+	var result *value.Int
+	if !lhs.IsBig() && !rhs.IsBig() {
+		if r, exact := op.small(int64(lhs.Value), int64(rhs.Value)); exact {
+			result = i.newInt64(r)
+		}
+	}
+
+	if result == nil {
+		r, err := op.big(lhs.Big(), rhs.Big())
+		if err != nil {
+			return nil, err
+		}
+
+		result = i.heap.NewBigInt(r)
+	}
+
+	if i.strict && (!fits32(lhs) || !fits32(rhs) || !fits32(result)) {
+		return nil, undefined("%v %v %v overflows the 32-bit range", lhs, op.name, rhs)
+	}
+
+	return result, nil
+}
+
+// newInt64 returns the given integer as an Int, which only needs to be a big
+// integer on platforms where an int has less than 64 bits.
+func (i *Interpreter) newInt64(n int64) *value.Int {
+	if int64(int(n)) != n {
+		return i.heap.NewBigInt(big.NewInt(n))
+	}
+
+	return i.heap.NewInt(int(n))
+}
+
+// Literal returns the given literal value as it's seen by the program, which
+// only differs from the literal itself for integers that don't fit in the
+// interpreter's integer semantics.
+// Backends should use it whenever they evaluate a literal.
+func (i *Interpreter) Literal(v value.Value) (value.Value, error) {
+	n, ok := v.(*value.Int)
+	if !ok {
+		return v, nil
+	}
+
+	return i.normalise(n)
+}
+
+// normalise returns the given integer as it's created with the interpreter's
+// integer semantics, which is how integers from literals, strings, and lengths
+// are seen by the rest of the program.
+// Integers are wrapped to 32 bits with IntWrap32 semantics, big integers are
+// wrapped to 64 bits with IntNative semantics, and big integers that don't fit
+// in 64 bits are an overflow with IntChecked64 semantics.
+//
+// In strict mode integers must fit in 32 bits.
+func (i *Interpreter) normalise(n *value.Int) (*value.Int, error) {
+	if fits32(n) {
+		return n, nil
+	}
+
+	if i.strict {
+		return nil, undefined("%v doesn't fit in the 32-bit range", n)
+	}
+
+	switch i.ints {
+	case IntWrap32:
+		v, _ := i.operand(n)
+
+		return i.heap.NewInt(int(v)), nil
+	case IntNative, IntChecked64:
+		if !n.IsBig() {
+			return n, nil
+		}
+
+		v, exact := i.operand(n)
+		if !exact && i.ints == IntChecked64 {
+			return nil, fmt.Errorf("%w: %v doesn't fit in 64 bits", ErrIntOverflow, n)
+		}
+
+		return i.newInt64(v), nil
+	}
+
+	return n, nil
+}
+
+// toInt converts the given value to an integer in the same way as the heap,
+// and then normalises it.
+func (i *Interpreter) toInt(v value.Value) (*value.Int, error) {
+	n, err := i.heap.ToInt(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.normalise(n)
+}
+
+// operand returns the given integer as it's seen by arithmetic operations
+// with every semantics other than IntBig, and whether that's exact.
+// Integers are wrapped to 32 bits with IntWrap32 semantics, and big integers
+// are wrapped to 64 bits otherwise.
+func (i *Interpreter) operand(n *value.Int) (int64, bool) {
+	v, exact := int64(n.Value), true
+	if n.IsBig() {
+		// Big integers can only fit in 64 bits on platforms where an int has
+		// less than 64 bits
+		if b := n.Big(); b.IsInt64() {
+			v = b.Int64()
+		} else {
+			mask := new(big.Int).SetUint64(math.MaxUint64)
+			v, exact = int64(new(big.Int).And(b, mask).Uint64()), false
+		}
+	}
+
+	if i.ints == IntWrap32 {
+		v = int64(int32(v))
+	}
+
+	return v, exact
+}
+
+// sign returns -1, 0, or 1 if the given integer is negative, zero, or positive
+// when it's used as an operand in arithmetic.
+func (i *Interpreter) sign(n *value.Int) int {
+	if i.ints == IntBig {
+		return n.Big().Sign()
+	}
+
+	v, _ := i.operand(n)
+	switch {
+	case v < 0:
+		return -1
+	case v > 0:
+		return 1
+	}
+
+	return 0
+}
+
+// fits32 reports whether the given integer fits in 32 bits.
+func fits32(n *value.Int) bool {
+	return !n.IsBig() && n.Value >= math.MinInt32 && n.Value <= math.MaxInt32
+}
+
+// add64 returns the wrapped sum of the given integers and whether it's exact.
+func add64(a, b int64) (int64, bool) {
+	r := a + b
+
+	return r, (a >= 0) != (b >= 0) || (r >= 0) == (a >= 0)
+}
+
+// sub64 returns the wrapped difference of the given integers and whether it's
+// exact.
+func sub64(a, b int64) (int64, bool) {
+	r := a - b
+
+	return r, (a >= 0) == (b >= 0) || (r >= 0) == (a >= 0)
+}
+
+// mul64 returns the wrapped product of the given integers and whether it's
+// exact.
+func mul64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+
+	r := a * b
+
+	return r, r/b == a && !(a == -1 && b == math.MinInt64) && !(b == -1 && a == math.MinInt64)
+}
+
+// div64 returns the quotient of the given integers truncated towards zero and
+// whether it's exact, which it only isn't when dividing the smallest integer
+// by -1.
+// The divisor must not be zero.
+func div64(a, b int64) (int64, bool) {
+	if a == math.MinInt64 && b == -1 {
+		return a, false
+	}
+
+	return a / b, true
+}
+
+// mod64 returns the remainder of dividing the given integers, which is always
+// exact.
+// The divisor must not be zero.
+func mod64(a, b int64) (int64, bool) {
+	if b == -1 {
+		return 0, true
+	}
+
+	return a % b, true
+}
+
+// pow64 returns the wrapped result of raising the given base to the given
+// power by repeated squaring, and whether it's exact.
+// Negative powers truncate towards zero, so they only have non-zero results
+// when the base is 1 or -1.
+// The base must not be zero if the power is negative.
+func pow64(base, power int64) (int64, bool) {
+	if power < 0 {
+		switch {
+		case base == 1:
+			return 1, true
+		case base == -1 && power%2 == 0:
+			return 1, true
+		case base == -1:
+			return -1, true
+		}
+
+		return 0, true
+	}
+
+	result, exact := int64(1), true
+	for power > 0 {
+		if power&1 == 1 {
+			var ok bool
+			result, ok = mul64(result, base)
+			exact = exact && ok
+		}
+
+		power >>= 1
+
+		// The base is only squared if it will be used again, so that an
+		// overflow here always means the result overflows too
+		if power > 0 {
+			var ok bool
+			base, ok = mul64(base, base)
+			exact = exact && ok
+		}
+	}
+
+	return result, exact
+}
+
+// powBig returns the result of raising the given base to the given power
+// exactly, with negative powers truncated towards zero in the same way as
+// pow64.
+// An error is returned if the result could have more than maxBigBits bits.
+func powBig(base, power *big.Int) (*big.Int, error) {
+	switch {
+	case power.Sign() == 0 || base.Cmp(big.NewInt(1)) == 0:
+		return big.NewInt(1), nil
+	case base.Cmp(big.NewInt(-1)) == 0 && power.Bit(0) == 0:
+		return big.NewInt(1), nil
+	case base.Cmp(big.NewInt(-1)) == 0:
+		return big.NewInt(-1), nil
+	case base.Sign() == 0 || power.Sign() < 0:
+		return new(big.Int), nil
+	}
+
+	// The number of bits in the base times the power is an upper bound on the
+	// number of bits in the result
+	if !power.IsInt64() || power.Int64() > maxBigBits || int64(base.BitLen())*power.Int64() > maxBigBits {
+		return nil, fmt.Errorf("%v to the power of %v is too large", base, power)
+	}
+
+	return new(big.Int).Exp(base, power, nil), nil
+}
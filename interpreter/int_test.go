@@ -0,0 +1,212 @@
+package interpreter_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/lexer"
+	"github.com/polyscone/knight/parser"
+	"github.com/polyscone/knight/value"
+)
+
+func TestWithIntSemantics(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name      string
+		source    string
+		semantics interpreter.IntSemantics
+		want      value.Value
+	}{
+		{"native exact exp", "^ 3 39", interpreter.IntNative, value.NewInt(4052555153018976267)},
+		{"native exact exp near max", "^ 7 22", interpreter.IntNative, value.NewInt(3909821048582988049)},
+		{"native add wraps", "+ 9223372036854775807 1", interpreter.IntNative, value.NewInt(-9223372036854775808)},
+		{"native exp wraps", "^ 3 41", interpreter.IntNative, value.NewInt(-420491770248316829)},
+		{"negative power", "^ 2 - 0 1", interpreter.IntNative, value.NewInt(0)},
+		{"negative power of one", "^ 1 - 0 5", interpreter.IntNative, value.NewInt(1)},
+		{"odd negative power of minus one", "^ - 0 1 - 0 3", interpreter.IntNative, value.NewInt(-1)},
+		{"even negative power of minus one", "^ - 0 1 - 0 4", interpreter.IntNative, value.NewInt(1)},
+		{"wrap32 add", "+ 2147483647 1", interpreter.IntWrap32, value.NewInt(-2147483648)},
+		{"wrap32 sub", "- - 0 2147483648 1", interpreter.IntWrap32, value.NewInt(2147483647)},
+		{"wrap32 mul", "* 65536 65536", interpreter.IntWrap32, value.NewInt(0)},
+		{"wrap32 div", "/ - 0 2147483648 - 0 1", interpreter.IntWrap32, value.NewInt(-2147483648)},
+		{"wrap32 exp", "^ 3 21", interpreter.IntWrap32, value.NewInt(1870418611)},
+		{"wrap32 exp beyond 64 bits", "^ 3 40", interpreter.IntWrap32, value.NewInt(689956897)},
+		{"wrap32 operands", "+ 4294967297 1", interpreter.IntWrap32, value.NewInt(2)},
+		{"wrap32 mod operands", "% 4294967303 10", interpreter.IntWrap32, value.NewInt(7)},
+		{"wrap32 in range", "* 46340 46340", interpreter.IntWrap32, value.NewInt(2147395600)},
+		{"wrap32 literal", "5000000000", interpreter.IntWrap32, value.NewInt(705032704)},
+		{"wrap32 literal equals sum", "? 5000000000 + 0 5000000000", interpreter.IntWrap32, value.NewBool(true)},
+		{"wrap32 block literal", "CALL BLOCK 5000000000", interpreter.IntWrap32, value.NewInt(705032704)},
+		{"wrap32 string", `< 0 "3000000000"`, interpreter.IntWrap32, value.NewBool(false)},
+		{"wrap32 string index", `GET "abc" "4294967297" 1`, interpreter.IntWrap32, value.NewString("b")},
+		{"checked64 in range", "* 4294967296 2147483647", interpreter.IntChecked64, value.NewInt(9223372032559808512)},
+		{"checked64 exp in range", "^ 2 62", interpreter.IntChecked64, value.NewInt(4611686018427387904)},
+		{"checked64 min", "- - 0 9223372036854775807 1", interpreter.IntChecked64, value.NewInt(-9223372036854775808)},
+		{"native big literal", "+ 0 18446744073709551617", interpreter.IntNative, value.NewInt(1)},
+		{"native big string", `+ 0 "18446744073709551618"`, interpreter.IntNative, value.NewInt(2)},
+		{"wrap32 big literal", "+ 0 18446744073709551619", interpreter.IntWrap32, value.NewInt(3)},
+		{"big small", "+ 1 2", interpreter.IntBig, value.NewInt(3)},
+		{"big add", "+ 9223372036854775807 1", interpreter.IntBig, bigInt("9223372036854775808")},
+		{"big sub", "- - 0 9223372036854775807 2", interpreter.IntBig, bigInt("-9223372036854775809")},
+		{"big factorial", "; = n 1 ; = i 1 ; WHILE < i 26 ; = n * n i = i + i 1 : n", interpreter.IntBig, bigInt("15511210043330985984000000")},
+		{"big exp", "^ 2 100", interpreter.IntBig, bigInt("1267650600228229401496703205376")},
+		{"big negative exp", "^ ^ 2 70 - 0 1", interpreter.IntBig, value.NewInt(0)},
+		{"big exp of minus one", "^ - 0 1 ^ 2 70", interpreter.IntBig, value.NewInt(1)},
+		{"big div back to small", "/ ^ 2 100 ^ 2 98", interpreter.IntBig, value.NewInt(4)},
+		{"big div truncates", "/ - 0 ^ 10 30 7", interpreter.IntBig, bigInt("-142857142857142857142857142857")},
+		{"big mod", "% ^ 10 30 7", interpreter.IntBig, value.NewInt(1)},
+		{"big literal", "* 123456789012345678901234567890 2", interpreter.IntBig, bigInt("246913578024691357802469135780")},
+		{"big string", `+ 1 "99999999999999999999"`, interpreter.IntBig, bigInt("100000000000000000000")},
+		{"big to string", `+ "" ^ 2 70`, interpreter.IntBig, value.NewString("1180591620717411303424")},
+		{"big less", "< ^ 2 64 ^ 2 65", interpreter.IntBig, value.NewBool(true)},
+		{"big equal", "? ^ 2 64 * ^ 2 32 ^ 2 32", interpreter.IntBig, value.NewBool(true)},
+		{"big length", "LENGTH ^ 2 70", interpreter.IntBig, value.NewInt(22)},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := runInts(t, tc.source, tc.semantics)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !value.Equal(tc.want, got) {
+				t.Errorf("want %v (%p), got %v (%p)", tc.want, tc.want, got, got)
+			}
+		})
+	}
+}
+
+func TestWithIntSemanticsErrors(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name      string
+		source    string
+		semantics interpreter.IntSemantics
+		want      error
+	}{
+		{"checked64 add", "+ 9223372036854775807 1", interpreter.IntChecked64, interpreter.ErrIntOverflow},
+		{"checked64 sub", "- - - 0 9223372036854775807 1 1", interpreter.IntChecked64, interpreter.ErrIntOverflow},
+		{"checked64 mul", "* 4294967296 4294967296", interpreter.IntChecked64, interpreter.ErrIntOverflow},
+		{"checked64 mul by minus one", "* - - 0 9223372036854775807 1 - 0 1", interpreter.IntChecked64, interpreter.ErrIntOverflow},
+		{"checked64 div", "/ - - 0 9223372036854775807 1 - 0 1", interpreter.IntChecked64, interpreter.ErrIntOverflow},
+		{"checked64 exp", "^ 2 63", interpreter.IntChecked64, interpreter.ErrIntOverflow},
+		{"checked64 exp beyond 128 bits", "^ 3 100", interpreter.IntChecked64, interpreter.ErrIntOverflow},
+		{"checked64 big literal", "+ 0 18446744073709551617", interpreter.IntChecked64, interpreter.ErrIntOverflow},
+		{"checked64 big string", `< 0 "18446744073709551617"`, interpreter.IntChecked64, interpreter.ErrIntOverflow},
+		{"big exp too large", "^ 2 ^ 2 30", interpreter.IntBig, nil},
+		{"big divide by zero", "/ ^ 2 70 0", interpreter.IntBig, nil},
+		{"big string index", `GET "abc" ^ 2 70 0`, interpreter.IntBig, nil},
+		{"wrap32 divide by wrapped zero", "/ 1 4294967296", interpreter.IntWrap32, nil},
+		{"wrap32 modulo by wrapped zero", "% 1 4294967296", interpreter.IntWrap32, nil},
+		{"wrap32 raise wrapped zero to negative power", "^ 4294967296 - 0 1", interpreter.IntWrap32, nil},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := runInts(t, tc.source, tc.semantics)
+			if err == nil {
+				t.Fatal("want error, got nil")
+			}
+
+			if tc.want != nil && !errors.Is(err, tc.want) {
+				t.Errorf("want %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestIntLiteralsAgree(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		semantics interpreter.IntSemantics
+		literal   string
+		want      string
+	}{
+		{interpreter.IntNative, "5000000000", "5000000000"},
+		{interpreter.IntNative, "18446744073709551617", "1"},
+		{interpreter.IntNative, "99999999999999999999", "7766279631452241919"},
+		{interpreter.IntWrap32, "5000000000", "705032704"},
+		{interpreter.IntWrap32, "18446744073709551617", "1"},
+		{interpreter.IntWrap32, "99999999999999999999", "1661992959"},
+		{interpreter.IntChecked64, "5000000000", "5000000000"},
+		{interpreter.IntChecked64, "18446744073709551617", ""},
+		{interpreter.IntChecked64, "99999999999999999999", ""},
+		{interpreter.IntBig, "5000000000", "5000000000"},
+		{interpreter.IntBig, "18446744073709551617", "18446744073709551617"},
+		{interpreter.IntBig, "99999999999999999999", "99999999999999999999"},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		// An empty want means the literal overflows
+		t.Run(tc.semantics.String()+" "+tc.literal, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout strings.Builder
+			_, err := runInts(t, "OUTPUT "+tc.literal, tc.semantics, interpreter.WithStdout(&stdout))
+			checkOverflow(t, "OUTPUT", tc.want, err)
+			if err == nil && stdout.String() != tc.want+"\n" {
+				t.Errorf("OUTPUT: want %q, got %q", tc.want+"\n", stdout.String())
+			}
+
+			equal, err := runInts(t, "? "+tc.literal+" + 0 "+tc.literal, tc.semantics)
+			checkOverflow(t, "?", tc.want, err)
+			if err == nil && !value.Equal(equal, value.NewBool(true)) {
+				t.Errorf("?: want true, got %v", equal)
+			}
+
+			sum, err := runInts(t, "+ 0 "+tc.literal, tc.semantics)
+			checkOverflow(t, "+ 0", tc.want, err)
+			if err == nil && sum.String() != tc.want {
+				t.Errorf("+ 0: want %v, got %v", tc.want, sum)
+			}
+		})
+	}
+}
+
+// checkOverflow reports an error unless the given error is an integer overflow when
+// want is empty, or nil otherwise.
+func checkOverflow(t *testing.T, name, want string, err error) {
+	t.Helper()
+
+	switch {
+	case want == "" && !errors.Is(err, interpreter.ErrIntOverflow):
+		t.Errorf("%v: want %v, got %v", name, interpreter.ErrIntOverflow, err)
+	case want != "" && err != nil:
+		t.Errorf("%v: want no error, got %v", name, err)
+	}
+}
+
+// runInts runs the given source with the given integer semantics and any
+// other options.
+func runInts(t *testing.T, source string, semantics interpreter.IntSemantics, opts ...interpreter.Option) (value.Value, error) {
+	t.Helper()
+
+	l := lexer.New()
+	p := parser.New(l)
+	g := value.NewGlobalStore()
+	program, err := p.Parse(g, strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts = append(opts, interpreter.WithIntSemantics(semantics))
+
+	return interpreter.New(g, p, opts...).Execute(program)
+}
+
+func bigInt(s string) value.Value {
+	return value.NewBigInt(value.AtoiBig(s))
+}
@@ -2,20 +2,21 @@ package interpreter
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
+	"math/rand"
 	"os"
+	"time"
 
 	"github.com/polyscone/knight/ast"
 	"github.com/polyscone/knight/token"
 	"github.com/polyscone/knight/value"
 )
 
-var stdin = bufio.NewReader(os.Stdin)
-
 // Parser should build a valid AST from some source code.
 type Parser interface {
-	Parse(globals *value.GlobalStore, r io.ByteScanner) (ast.Program, error)
+	ParseFile(globals *value.GlobalStore, filename string, r io.ByteScanner) (ast.Program, error)
 }
 
 // Interpreter is an implementation of a tree-walk interpreter than can execute
@@ -23,153 +24,412 @@ type Parser interface {
 type Interpreter struct {
 	globals *value.GlobalStore
 	parser  Parser
+	stack   []frame
+	calls   []pendingCall
+
+	maxSteps int
+	steps    int
+	maxDepth int
+	strict   bool
+	ints     IntSemantics
+	dump     DumpFormat
+
+	ctx  context.Context
+	done <-chan struct{}
+
+	runtime *Runtime
+	heap    *value.Heap
+
+	stdin  *bufio.Reader
+	stdout *bufio.Writer
+	stderr io.Writer
+
+	runner CommandRunner
+
+	rand       *rand.Rand
+	seed       int64
+	seedKnown  bool
+	randomUsed bool
+
+	backend Backend
+}
+
+// frame records an active block call so that a Knight stack trace can be
+// attached to runtime errors.
+type frame struct {
+	name string
+	site ast.Span
 }
 
 // Execute will walk the given program's AST executing nodes as it goes.
 func (i *Interpreter) Execute(program ast.Program) (value.Value, error) {
-	return i.eval(program.Root)
+	return i.ExecuteContext(context.Background(), program)
 }
 
+// ExecuteContext works the same way as Execute, but will stop executing the
+// program and return an error that wraps ctx.Err() if the given context is
+// cancelled.
+//
+// Cancellation is checked on every loop iteration, block call, and EVAL, and
+// any SYSTEM command that's running when the context is cancelled is killed.
+//
+// Any buffered output is flushed before returning.
+// If the program calls QUIT then the returned error will be an *ExitError.
+func (i *Interpreter) ExecuteContext(ctx context.Context, program ast.Program) (value.Value, error) {
+	i.stack = i.stack[:0]
+	i.calls = i.calls[:0]
+	i.steps = 0
+	i.ctx = ctx
+	i.done = ctx.Done()
+
+	defer func() {
+		i.ctx = context.Background()
+		i.done = nil
+	}()
+
+	var val value.Value
+	var err error
+	if i.backend != nil {
+		val, err = i.backend.Run(i, program.Root)
+	} else {
+		val, err = i.eval(program.Root)
+	}
+	if flushErr := i.stdout.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+// Globals returns every global known to the interpreter in the order that
+// they were declared, including any that were declared by EVAL.
+func (i *Interpreter) Globals() []*value.Global {
+	if i.globals == nil {
+		return nil
+	}
+
+	return i.globals.All()
+}
+
+// Runtime returns the runtime that holds the interpreter's program state.
+func (i *Interpreter) Runtime() *Runtime {
+	return i.runtime
+}
+
+// eval evaluates the given node and returns its result.
+//
+// Expressions in tail position, which are the RHS of a chain, the branches of
+// an IF, the RHS of & and |, the argument to CALL, and the bodies of called
+// blocks, are evaluated by looping here rather than by recursing, so a program
+// only grows the Go stack when it makes calls that aren't in tail position.
+//
+// A call that's made in tail position pops the caller's frame from the Knight
+// call stack before pushing its own, so tail recursion doesn't count towards
+// the maximum call depth, but the caller won't appear in stack traces.
+// A CALL whose argument is still being evaluated is recorded on the
+// interpreter's list of pending calls, so that its result can be called once
+// it's known; since the argument is also in tail position the caller's frame
+// can be popped before that happens.
+// Every frame and pending call that's pushed here is popped before returning.
 func (i *Interpreter) eval(node ast.Node) (value.Value, error) {
-	switch v := node.(type) {
-	case *value.Bool:
-		return v, nil
-	case *value.Int:
-		return v, nil
-	case *value.String:
-		return v, nil
-	case *value.Null:
-		return v, nil
-	case *value.Global:
-		if v.Value == nil {
-			return nil, fmt.Errorf("attempted to access undefined variable %v", node)
-		}
+	stackBase, callBase := len(i.stack), len(i.calls)
 
-		if b, ok := v.Value.(*value.Block); ok {
-			return i.eval(b.Value.(ast.Node))
+loop:
+	for {
+		if err := i.Step(); err != nil {
+			return nil, i.unwind(stackBase, callBase, i.locate(node, err))
 		}
 
-		return v.Value, nil
-	case *ast.Call:
-		switch v.Letter {
-		case 'A':
-			val, err := i.eval(v.Args[0])
-			if err != nil {
-				return nil, err
+		var val value.Value
+
+		switch v := node.(type) {
+		case *value.Variable:
+			if b, ok := v.Global.Value.(*value.Block); ok && !i.strict {
+				if err := i.enter(v.Global.Name, v.Span, stackBase, callBase); err != nil {
+					return nil, i.unwind(stackBase, callBase, i.locate(node, err))
+				}
+
+				node = b.Value
+
+				continue
 			}
+		case *ast.CallBlock:
+			i.calls = append(i.calls, pendingCall{site: v.Span, depth: i.floor(stackBase, callBase)})
+			node = v.Block
 
-			return i.ASCII(val)
-		case 'B':
-			return i.Block(v.Args[0])
-		case 'C':
-			arg, err := i.eval(v.Args[0])
+			continue
+		case *ast.If:
+			condition, err := i.eval(v.Condition)
 			if err != nil {
-				return nil, err
+				return nil, i.unwind(stackBase, callBase, i.locate(node, err))
 			}
 
-			return i.Call(arg)
-		case 'D':
-			val, err := i.eval(v.Args[0])
+			b, err := value.ToBool(condition)
 			if err != nil {
-				return nil, err
+				return nil, i.unwind(stackBase, callBase, i.locate(node, err))
 			}
 
-			return i.Dump(os.Stdout, val)
-		case 'E':
-			val, err := i.eval(v.Args[0])
-			if err != nil {
-				return nil, err
+			node = v.Alternative
+			if b.Value {
+				node = v.Consequence
 			}
 
-			return i.Eval(val)
-		case 'G':
-			_ = v.Args[2]
-			arg0, arg1, arg2 := v.Args[0], v.Args[1], v.Args[2]
+			continue
+		case *ast.Unary:
+			if v.Op == token.Noop {
+				node = v.Node
 
-			str, err := i.eval(arg0)
-			if err != nil {
-				return nil, err
+				continue
 			}
-
-			start, err := i.eval(arg1)
-			if err != nil {
-				return nil, err
+		case *ast.Binary:
+			switch v.Op {
+			case token.Chain:
+				if _, err := i.eval(v.LHS); err != nil {
+					return nil, i.unwind(stackBase, callBase, i.locate(node, err))
+				}
+
+				node = v.RHS
+
+				continue
+			case token.And, token.Or:
+				lhs, err := i.eval(v.LHS)
+				if err != nil {
+					return nil, i.unwind(stackBase, callBase, i.locate(node, err))
+				}
+
+				b, err := value.ToBool(lhs)
+				if err != nil {
+					return nil, i.unwind(stackBase, callBase, i.locate(node, err))
+				}
+
+				// The RHS is only evaluated if the LHS doesn't short-circuit
+				if b.Value == (v.Op == token.And) {
+					node = v.RHS
+
+					continue
+				}
+
+				val = lhs
 			}
+		}
 
-			count, err := i.eval(arg2)
-			if err != nil {
-				return nil, err
-			}
+		if val == nil {
+			var err error
+			if val, err = i.evalNode(node); err != nil {
+				// Literals don't have a location, so an error from the
+				// literal body of a called block is located at the call
+				if _, ok := ast.Locate(node); !ok && len(i.stack) > i.floor(stackBase, callBase) {
+					site := i.stack[len(i.stack)-1].site
+					i.Leave()
 
-			return i.Get(str, start, count)
-		case 'I':
-			_ = v.Args[2]
-			arg0, arg1, arg2 := v.Args[0], v.Args[1], v.Args[2]
+					return nil, i.unwind(stackBase, callBase, i.Locate(site, err))
+				}
 
-			condition, err := i.eval(arg0)
-			if err != nil {
-				return nil, err
+				return nil, i.unwind(stackBase, callBase, i.locate(node, err))
 			}
+		}
 
-			return i.If(condition, arg1, arg2)
-		case 'L':
-			val, err := i.eval(v.Args[0])
-			if err != nil {
-				return nil, err
-			}
+		// Calling anything other than a block just returns it, so the result
+		// is final once there are no pending calls left that have a block to
+		// call
+		for len(i.calls) > callBase {
+			call := i.calls[len(i.calls)-1]
+			i.calls = i.calls[:len(i.calls)-1]
 
-			return i.Length(val)
-		case 'O':
-			val, err := i.eval(v.Args[0])
-			if err != nil {
-				return nil, err
-			}
+			// Anything that was called while evaluating the argument to
+			// CALL has returned by now, and so has the caller
+			i.stack = i.stack[:call.depth]
 
-			return i.Output(os.Stdout, val)
-		case 'P':
-			return i.Prompt(stdin)
-		case 'Q':
-			val, err := i.eval(v.Args[0])
-			if err != nil {
-				return nil, err
-			}
+			if b, ok := val.(*value.Block); ok {
+				if err := i.enter("<block>", call.site, stackBase, callBase); err != nil {
+					return nil, i.unwind(stackBase, callBase, i.Locate(call.site, err))
+				}
 
-			return i.Quit(val)
-		case 'R':
-			return i.Random()
-		case 'S':
-			_ = v.Args[3]
-			arg0, arg1, arg2, arg3 := v.Args[0], v.Args[1], v.Args[2], v.Args[3]
+				node = b.Value
 
-			str, err := i.eval(arg0)
-			if err != nil {
-				return nil, err
+				continue loop
 			}
 
-			start, err := i.eval(arg1)
-			if err != nil {
-				return nil, err
+			if err := i.checkCall(val); err != nil {
+				return nil, i.unwind(stackBase, callBase, i.Locate(call.site, err))
 			}
+		}
 
-			count, err := i.eval(arg2)
-			if err != nil {
-				return nil, err
-			}
+		return val, i.unwind(stackBase, callBase, nil)
+	}
+}
 
-			replacement, err := i.eval(arg3)
-			if err != nil {
-				return nil, err
-			}
+// pendingCall records a CALL whose argument is still being evaluated.
+type pendingCall struct {
+	site  ast.Span
+	depth int
+}
+
+// floor returns the depth of the Knight call stack below which the eval loop
+// with the given bases doesn't own any frames.
+// The loop owns at most one frame, which is the one for the block it's
+// currently evaluating, and it's above the floor.
+func (i *Interpreter) floor(stackBase, callBase int) int {
+	if len(i.calls) > callBase {
+		return i.calls[len(i.calls)-1].depth
+	}
+
+	return stackBase
+}
+
+// enter pushes a frame onto the Knight call stack for a call made by the eval
+// loop with the given bases.
+// Every call made by the loop is in tail position, so if the loop owns the
+// frame on the top of the stack then the caller is done with it, and it's
+// popped first.
+func (i *Interpreter) enter(name string, site ast.Span, stackBase, callBase int) error {
+	if len(i.stack) > i.floor(stackBase, callBase) {
+		i.Leave()
+	}
+
+	return i.Enter(name, site)
+}
+
+// unwind pops the Knight call stack and the pending calls back to the given
+// lengths and then returns the given error.
+// Errors should already have been located so that their stack trace includes
+// the frames that are being popped.
+func (i *Interpreter) unwind(stackBase, callBase int, err error) error {
+	i.stack = i.stack[:stackBase]
+	i.calls = i.calls[:callBase]
+
+	return err
+}
+
+// evalNode evaluates every node that isn't handled by eval.
+func (i *Interpreter) evalNode(node ast.Node) (value.Value, error) {
+	switch v := node.(type) {
+	case *value.Bool:
+		return v, nil
+	case *value.Int:
+		return i.normalise(v)
+	case *value.String:
+		return v, nil
+	case *value.Null:
+		return v, nil
+	case *value.List:
+		return v, nil
+	case *value.Variable:
+		g := v.Global
+		if g.Value == nil {
+			return nil, fmt.Errorf("attempted to access undefined variable %q", g.Name)
+		}
+
+		return g.Value, nil
+	case *ast.ASCII:
+		val, err := i.eval(v.Arg)
+		if err != nil {
+			return nil, err
+		}
+
+		return i.ASCII(val)
+	case *ast.Block:
+		return i.Block(v.Body)
+	case *ast.Dump:
+		val, err := i.eval(v.Arg)
+		if err != nil {
+			return nil, err
+		}
+
+		return i.Dump(i.stdout, val)
+	case *ast.Eval:
+		val, err := i.eval(v.Arg)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := i.Enter("<eval>", v.Span); err != nil {
+			return nil, err
+		}
+
+		val, err = i.Eval(val)
+		i.Leave()
+
+		return val, err
+	case *ast.Get:
+		str, err := i.eval(v.Str)
+		if err != nil {
+			return nil, err
+		}
+
+		start, err := i.eval(v.Start)
+		if err != nil {
+			return nil, err
+		}
 
-			return i.Substitute(str, start, count, replacement)
-		case 'W':
-			_ = v.Args[1]
-			arg0, arg1 := v.Args[0], v.Args[1]
+		count, err := i.eval(v.Count)
+		if err != nil {
+			return nil, err
+		}
 
-			return i.While(arg0, arg1)
+		return i.Get(str, start, count)
+	case *ast.Length:
+		val, err := i.eval(v.Arg)
+		if err != nil {
+			return nil, err
 		}
 
+		return i.Length(val)
+	case *ast.Output:
+		val, err := i.eval(v.Arg)
+		if err != nil {
+			return nil, err
+		}
+
+		return i.Output(i.stdout, val)
+	case *ast.Prompt:
+		// Make sure any prompt text has actually been written before
+		// blocking on input
+		if err := i.Flush(); err != nil {
+			return nil, err
+		}
+
+		return i.Prompt(i.stdin)
+	case *ast.Quit:
+		val, err := i.eval(v.Arg)
+		if err != nil {
+			return nil, err
+		}
+
+		return i.Quit(val)
+	case *ast.Random:
+		return i.Random()
+	case *ast.Substitute:
+		str, err := i.eval(v.Str)
+		if err != nil {
+			return nil, err
+		}
+
+		start, err := i.eval(v.Start)
+		if err != nil {
+			return nil, err
+		}
+
+		count, err := i.eval(v.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		replacement, err := i.eval(v.Replacement)
+		if err != nil {
+			return nil, err
+		}
+
+		return i.Substitute(str, start, count, replacement)
+	case *ast.While:
+		return i.While(v.Condition, v.Body)
+	case *ast.Call:
 		return nil, fmt.Errorf("undefined function: %v", v.Name)
+	case Native:
+		return v.Eval(i)
 	case *ast.Unary:
 		val, err := i.eval(v.Node)
 		if err != nil {
@@ -183,6 +443,12 @@ func (i *Interpreter) eval(node ast.Node) (value.Value, error) {
 			return i.Noop(val)
 		case token.System:
 			return i.System(val)
+		case token.Box:
+			return i.Box(val)
+		case token.Head:
+			return i.Head(val)
+		case token.Tail:
+			return i.Tail(val)
 		default:
 			return nil, fmt.Errorf("unknown unary operator: %s", v)
 		}
@@ -227,15 +493,13 @@ func (i *Interpreter) eval(node ast.Node) (value.Value, error) {
 			return i.Or(lhs, v.RHS)
 		case token.Equal:
 			return i.Equal(lhs, rhs)
-		case token.Chain:
-			return rhs, nil
 		case token.Assign:
-			global, ok := v.LHS.(*value.Global)
+			variable, ok := v.LHS.(*value.Variable)
 			if !ok {
 				return nil, fmt.Errorf("cannot assign to %s", v.LHS)
 			}
 
-			return i.Assign(global, rhs)
+			return i.Assign(variable.Global, rhs)
 		default:
 			return nil, fmt.Errorf("unknown binary operator: %s", v)
 		}
@@ -246,9 +510,37 @@ func (i *Interpreter) eval(node ast.Node) (value.Value, error) {
 
 // New returns an initialised Interpreter that can be used to execute programs
 // that are represented as an AST.
-func New(globals *value.GlobalStore, parser Parser) *Interpreter {
-	return &Interpreter{
-		globals: globals,
-		parser:  parser,
+func New(globals *value.GlobalStore, parser Parser, opts ...Option) *Interpreter {
+	seed := time.Now().UnixNano()
+
+	i := &Interpreter{
+		globals:  globals,
+		parser:   parser,
+		ctx:      context.Background(),
+		maxDepth: DefaultMaxDepth,
+		stdout:   bufio.NewWriter(os.Stdout),
+		stderr:   os.Stderr,
+		runner:   ShellRunner{},
+
+		//nolint:gosec // there's no need for a CSPRNG here
+		rand:      rand.New(rand.NewSource(seed)),
+		seed:      seed,
+		seedKnown: true,
+	}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	if i.runtime == nil {
+		i.runtime = NewRuntime(nil)
 	}
+
+	i.heap = i.runtime.heap
+
+	if i.stdin == nil {
+		i.stdin = i.runtime.stdin
+	}
+
+	return i
 }
@@ -0,0 +1,123 @@
+package interpreter_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/lexer"
+	"github.com/polyscone/knight/parser"
+	"github.com/polyscone/knight/value"
+)
+
+func TestExecuteContext(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		source string
+		unix   bool
+	}{
+		{"infinite loop", "WHILE T 1", false},
+		{"block called in an infinite loop", "; = f BLOCK 1 : WHILE T CALL f", false},
+		{"infinite recursion through eval", `; = f "EVAL f" : EVAL f`, false},
+		{"long running system command", "` 'sleep 10'", true},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.unix && runtime.GOOS == "windows" {
+				t.Skip("requires a unix shell")
+			}
+
+			l := lexer.New()
+			p := parser.New(l)
+			g := value.NewGlobalStore()
+			program, err := p.Parse(g, strings.NewReader(tc.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			_, err = interpreter.New(g, p, interpreter.WithStderr(io.Discard)).ExecuteContext(ctx, program)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("want %v, got %v", context.DeadlineExceeded, err)
+			}
+
+			var rerr *interpreter.RuntimeError
+			if !errors.As(err, &rerr) {
+				t.Errorf("want a runtime error with a stack, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExecuteContextQuit(t *testing.T) {
+	t.Parallel()
+
+	l := lexer.New()
+	p := parser.New(l)
+	g := value.NewGlobalStore()
+	program, err := p.Parse(g, strings.NewReader("QUIT 3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The program never checks for cancellation, so it still quits
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = interpreter.New(g, p).ExecuteContext(ctx, program)
+
+	var exit *interpreter.ExitError
+	if !errors.As(err, &exit) || exit.Code != 3 {
+		t.Errorf("want exit code 3, got %v", err)
+	}
+}
+
+func TestGlobals(t *testing.T) {
+	t.Parallel()
+
+	l := lexer.New()
+	p := parser.New(l)
+	g := value.NewGlobalStore()
+	program, err := p.Parse(g, strings.NewReader(`; = b 1 ; = a 2 ; EVAL "= c + a b" : = b 3`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := interpreter.New(g, p)
+	if _, err := i.Execute(program); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		name  string
+		value value.Value
+	}{
+		{"b", value.NewInt(3)},
+		{"a", value.NewInt(2)},
+		{"c", value.NewInt(3)},
+	}
+
+	globals := i.Globals()
+	if len(globals) != len(want) {
+		t.Fatalf("want %v globals, got %v", len(want), len(globals))
+	}
+
+	for n, global := range globals {
+		if global.Name != want[n].name || !value.Equal(global.Value, want[n].value) {
+			t.Errorf("want global %v = %v, got %v = %v", want[n].name, want[n].value, global.Name, global.Value)
+		}
+	}
+}
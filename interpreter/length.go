@@ -2,7 +2,17 @@ package interpreter
 
 import "github.com/polyscone/knight/value"
 
-// Length returns the length in bytes of the given value when converted to a string.
+// Length returns the number of values in the given list, or otherwise the
+// length in bytes of the given value when converted to a string.
 func (i *Interpreter) Length(val value.Value) (value.Value, error) {
-	return value.NewInt(len(val.AsString().Value)), nil
+	if l, ok := val.(*value.List); ok {
+		return i.normalise(i.heap.NewInt(l.Len()))
+	}
+
+	s, err := i.heap.ToString(val)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.normalise(i.heap.NewInt(s.Len()))
 }
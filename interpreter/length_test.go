@@ -36,3 +36,33 @@ func TestLength(t *testing.T) {
 		})
 	}
 }
+
+func TestLengthList(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name string
+		list value.Value
+		want value.Value
+	}{
+		{"empty 0", value.NewList(nil), value.NewInt(0)},
+		{"one value 1", list(value.NewString("foo")), value.NewInt(1)},
+		{"nested list 2", list(value.NewInt(1), list(value.NewInt(2), value.NewInt(3))), value.NewInt(2)},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := interpreter.New(nil, nil).Length(tc.list)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !value.Equal(result, tc.want) {
+				t.Errorf("want %v (%p), got %v (%p)", tc.want, tc.want, result, result)
+			}
+		})
+	}
+}
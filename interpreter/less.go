@@ -1,25 +1,18 @@
 package interpreter
 
-import (
-	"fmt"
-
-	"github.com/polyscone/knight/value"
-)
+import "github.com/polyscone/knight/value"
 
 // Less returns the boolean result of a less than (<) comparison between
 // the LHS and RHS values.
 //
-// The LHS value can be either a boolean, integer, or string, and the RHS value
-// will be converted to the same type.
+// The LHS value can be either a boolean, integer, string, or list, and the RHS
+// value will be converted to the same type.
+// Lists are compared lexicographically, comparing their values in the same way.
 func (i *Interpreter) Less(lhs, rhs value.Value) (value.Value, error) {
-	switch lhs := lhs.(type) {
-	case *value.Bool:
-		return value.NewBool(!lhs.Value && rhs.AsBool().Value), nil
-	case *value.Int:
-		return value.NewBool(lhs.Value < rhs.AsInt().Value), nil
-	case *value.String:
-		return value.NewBool(lhs.Value < rhs.AsString().Value), nil
+	c, err := i.compare(lhs, rhs)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("cannot compare %s and %s", lhs, rhs)
+	return value.NewBool(c < 0), nil
 }
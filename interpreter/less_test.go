@@ -22,6 +22,11 @@ func TestLess(t *testing.T) {
 		{"2 is not less than 1", value.NewInt(2), value.NewInt(1), false},
 		{"a is less than b", value.NewString("a"), value.NewString("b"), true},
 		{"b is not less than a", value.NewString("b"), value.NewString("a"), false},
+		{"list is less than list with greater element", list(value.NewInt(1), value.NewInt(2)), list(value.NewInt(1), value.NewInt(3)), true},
+		{"list with lesser first element is less", list(value.NewInt(1), value.NewInt(3)), list(value.NewInt(2)), true},
+		{"prefix is less than list", list(value.NewInt(1)), list(value.NewInt(1), value.NewInt(2)), true},
+		{"list is not less than itself", list(value.NewInt(1)), list(value.NewInt(1)), false},
+		{"list is less than converted string", list(value.NewString("a")), value.NewString("ab"), true},
 	}
 	for _, tc := range tt {
 		tc := tc
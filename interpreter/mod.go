@@ -10,15 +10,22 @@ import (
 // The LHS value must be an integer, and the RHS value will be converted to an
 // integer if it isn't one already.
 //
-// The RHS value must be a positive number.
+// The RHS value must be a positive number, and in strict mode the LHS value
+// can't be negative.
 func (i *Interpreter) Mod(lhs, rhs value.Value) (value.Value, error) {
 	if lhs, ok := lhs.(*value.Int); ok {
-		rhs := rhs.AsInt().Value
-		if rhs <= 0 {
+		rhs, err := i.toInt(rhs)
+		if err != nil {
+			return nil, err
+		}
+		if i.sign(rhs) <= 0 {
 			return nil, fmt.Errorf("cannot modulo by %v", rhs)
 		}
+		if i.strict && i.sign(lhs) < 0 {
+			return nil, undefined("cannot take the modulo of negative number %v", lhs)
+		}
 
-		return value.NewInt(lhs.Value % rhs), nil
+		return i.arith(opMod, lhs, rhs)
 	}
 
 	return nil, fmt.Errorf("cannot divide %s by %s", lhs, rhs)
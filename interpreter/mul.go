@@ -2,6 +2,7 @@ package interpreter
 
 import (
 	"fmt"
+	"math"
 	"strings"
 
 	"github.com/polyscone/knight/value"
@@ -10,21 +11,57 @@ import (
 // Mul returns the product of the LHS and RHS values.
 //
 // If the LHS value is an integer then the result will be the multiplication of
-// the LHS value with the integer conversion of the RHS value.
+// the LHS value with the integer conversion of the RHS value, which overflows
+// according to the interpreter's integer semantics.
 //
 // If the LHS value is a string then the result will be the LSH value repeated
-// the number of times specified by the integer conversion of the RHS value.
+// the number of times specified by the integer conversion of the RHS value,
+// and the same goes for lists.
 func (i *Interpreter) Mul(lhs, rhs value.Value) (value.Value, error) {
 	switch lhs := lhs.(type) {
 	case *value.Int:
-		return value.NewInt(lhs.Value * rhs.AsInt().Value), nil
+		rhs, err := i.toInt(rhs)
+		if err != nil {
+			return nil, err
+		}
+
+		return i.arith(opMul, lhs, rhs)
 	case *value.String:
-		count := rhs.AsInt().Value
+		rhs, err := i.toInt(rhs)
+		if err != nil {
+			return nil, err
+		}
+
+		count := rhs.Value
 		if count < 0 {
 			return nil, fmt.Errorf("invalid string repeat count %v", count)
 		}
+		if count > 0 && lhs.Len() > math.MaxInt32/count {
+			return nil, fmt.Errorf("string repeat count %v is too large", count)
+		}
+
+		return i.heap.NewString(strings.Repeat(lhs.Value(), count)), nil
+	case *value.List:
+		rhs, err := i.toInt(rhs)
+		if err != nil {
+			return nil, err
+		}
+
+		count := rhs.Value
+		if count < 0 {
+			return nil, fmt.Errorf("invalid list repeat count %v", count)
+		}
+
+		if count > 0 && lhs.Len() > math.MaxInt32/count {
+			return nil, fmt.Errorf("list repeat count %v is too large", count)
+		}
+
+		values := make([]value.Value, 0, lhs.Len()*count)
+		for n := 0; n < count; n++ {
+			values = append(values, lhs.Value...)
+		}
 
-		return value.NewString(strings.Repeat(lhs.Value, count)), nil
+		return value.NewList(values), nil
 	}
 
 	return nil, fmt.Errorf("cannot subtract %s from %s", rhs, lhs)
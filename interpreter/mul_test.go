@@ -20,6 +20,8 @@ func TestMul(t *testing.T) {
 		{"lhs negative int, rhs negative int", value.NewInt(-1), value.NewInt(-2), value.NewInt(2)},
 		{"lhs positive int, rhs string", value.NewInt(25), value.NewString("10"), value.NewInt(250)},
 		{"lhs string, rhs positive int", value.NewString("foo"), value.NewInt(3), value.NewString("foofoofoo")},
+		{"lhs list, rhs positive int", list(value.NewInt(1), value.NewBool(true)), value.NewInt(2), list(value.NewInt(1), value.NewBool(true), value.NewInt(1), value.NewBool(true))},
+		{"lhs list, rhs zero", list(value.NewInt(1)), value.NewInt(0), value.NewList(nil)},
 	}
 	for _, tc := range tt {
 		tc := tc
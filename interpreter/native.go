@@ -0,0 +1,20 @@
+package interpreter
+
+import (
+	"github.com/polyscone/knight/ast"
+	"github.com/polyscone/knight/value"
+)
+
+// Native is implemented by AST nodes that evaluate themselves with Go code
+// instead of being walked by the interpreter, like the blocks of a program
+// that has been transpiled to Go.
+//
+// Native nodes can be wrapped in blocks and called from code that is run by
+// the interpreter, so a transpiled program's blocks can still be called from
+// code that it runs with EVAL.
+// They're only supported by the tree-walk evaluator.
+type Native interface {
+	ast.Node
+
+	Eval(i *Interpreter) (value.Value, error)
+}
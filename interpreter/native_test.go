@@ -0,0 +1,55 @@
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/polyscone/knight/ast"
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/lexer"
+	"github.com/polyscone/knight/parser"
+	"github.com/polyscone/knight/value"
+)
+
+type double struct {
+	g *value.Global
+}
+
+func (d double) String() string {
+	return "double"
+}
+
+func (d double) ASTString(ast.Style) string {
+	return "double"
+}
+
+func (d double) Eval(i *interpreter.Interpreter) (value.Value, error) {
+	return i.Mul(d.g.Value, value.NewInt(2))
+}
+
+func TestNative(t *testing.T) {
+	t.Parallel()
+
+	g := value.NewGlobalStore()
+	x := g.New("x")
+	x.Value = value.NewInt(21)
+	g.New("f").Value = value.NewBlock(double{g: x})
+
+	i := interpreter.New(g, parser.New(lexer.New()))
+
+	call, err := i.ParseEval(value.NewString("CALL f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Native nodes can be run directly, and called as blocks by parsed code
+	for _, program := range []ast.Program{{Root: double{g: x}}, call} {
+		result, err := i.Execute(program)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := value.NewInt(42); !value.Equal(result, want) {
+			t.Errorf("want %v, got %v", want, result)
+		}
+	}
+}
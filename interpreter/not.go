@@ -4,5 +4,10 @@ import "github.com/polyscone/knight/value"
 
 // Not returns the negation of the boolean conversion of the given value.
 func (i *Interpreter) Not(val value.Value) (value.Value, error) {
-	return value.NewBool(!val.AsBool().Value), nil
+	b, err := value.ToBool(val)
+	if err != nil {
+		return nil, err
+	}
+
+	return value.NewBool(!b.Value), nil
 }
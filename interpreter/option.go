@@ -0,0 +1,151 @@
+package interpreter
+
+import (
+	"bufio"
+	"io"
+	"math/rand"
+)
+
+// Option configures an Interpreter when it is created with New.
+type Option func(i *Interpreter)
+
+// WithMaxSteps limits the number of nodes that a single call to Execute is
+// allowed to evaluate, including nodes in blocks, loops, and code run by EVAL.
+// When the limit is reached execution stops with ErrBudgetExceeded.
+//
+// A limit of zero or less means there is no limit, which is the default.
+func WithMaxSteps(n int) Option {
+	return func(i *Interpreter) {
+		i.maxSteps = n
+	}
+}
+
+// WithStrict turns on strict mode, in which anything that the Knight spec
+// leaves undefined is reported as an error that wraps ErrUndefined rather than
+// being given whatever meaning is convenient.
+// This includes calling values that aren't blocks, integers that overflow 32
+// bits, and comparing, dumping, or indexing values of the wrong type.
+//
+// Strict mode also turns off calling blocks that are held by variables when the
+// variable is evaluated, so a block is only run when it's passed to CALL.
+func WithStrict(strict bool) Option {
+	return func(i *Interpreter) {
+		i.strict = strict
+	}
+}
+
+// WithIntSemantics sets how integer arithmetic behaves when a result doesn't
+// fit in an integer.
+// The default is IntNative.
+func WithIntSemantics(s IntSemantics) Option {
+	return func(i *Interpreter) {
+		i.ints = s
+	}
+}
+
+// WithDumpFormat sets the format that DUMP prints values in.
+// The default is DumpLegacy.
+func WithDumpFormat(f DumpFormat) Option {
+	return func(i *Interpreter) {
+		i.dump = f
+	}
+}
+
+// DefaultMaxDepth is the maximum call depth used when WithMaxDepth isn't given.
+// Calls that aren't in tail position are evaluated recursively, so this keeps
+// deeply recursive programs well within the limits of the Go stack.
+const DefaultMaxDepth = 100000
+
+// WithMaxDepth limits the number of nested block calls and EVALs that can be
+// active at once, which is the depth of the Knight call stack.
+// Calls made in tail position replace their caller, so they don't add to the
+// depth.
+// When the limit is reached execution stops with ErrMaxDepthExceeded.
+//
+// A limit of zero or less means there is no limit, in which case a program
+// that recurses deeply enough can overflow the Go stack, which is fatal.
+// The default is DefaultMaxDepth.
+func WithMaxDepth(n int) Option {
+	return func(i *Interpreter) {
+		i.maxDepth = n
+	}
+}
+
+// WithStdin sets the reader that PROMPT reads lines from.
+// The default is the reader given to the interpreter's runtime.
+func WithStdin(r io.Reader) Option {
+	return func(i *Interpreter) {
+		i.stdin = bufio.NewReader(r)
+	}
+}
+
+// WithStdout sets the writer that OUTPUT and DUMP write to.
+// Writes are buffered and flushed when execution finishes, when QUIT is
+// called, and before PROMPT reads any input.
+// The default is os.Stdout.
+func WithStdout(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.stdout = bufio.NewWriter(w)
+	}
+}
+
+// WithStderr sets the writer that the standard error of SYSTEM commands is
+// written to.
+// The default is os.Stderr.
+func WithStderr(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.stderr = w
+	}
+}
+
+// WithCommandRunner sets the CommandRunner that SYSTEM uses to run commands.
+// The default is ShellRunner.
+func WithCommandRunner(r CommandRunner) Option {
+	return func(i *Interpreter) {
+		i.runner = r
+	}
+}
+
+// WithSeed seeds the interpreter's source of pseudo-random numbers for RANDOM
+// so that programs can be run deterministically.
+// By default a source is created with a seed based on the current time.
+func WithSeed(seed int64) Option {
+	return func(i *Interpreter) {
+		//nolint:gosec // there's no need for a CSPRNG here
+		i.rand = rand.New(rand.NewSource(seed))
+		i.seed = seed
+		i.seedKnown = true
+	}
+}
+
+// WithRandSource sets the source of pseudo-random numbers used by RANDOM.
+// The source is not safe for concurrent use, so it should not be shared with
+// other interpreters running at the same time.
+func WithRandSource(src rand.Source) Option {
+	return func(i *Interpreter) {
+		//nolint:gosec // there's no need for a CSPRNG here
+		i.rand = rand.New(src)
+		i.seed = 0
+		i.seedKnown = false
+	}
+}
+
+// WithBackend sets the Backend used to execute programs.
+// By default programs are executed by walking the AST directly.
+func WithBackend(b Backend) Option {
+	return func(i *Interpreter) {
+		i.backend = b
+	}
+}
+
+// WithRuntime sets the runtime that holds the interpreter's heap, EVAL program
+// cache, and stdin reader.
+// By default every interpreter has its own runtime that reads from os.Stdin.
+//
+// A runtime is safe to share between interpreters, but interpreters that share
+// a runtime will contend on it, and resetting it affects all of them.
+func WithRuntime(rt *Runtime) Option {
+	return func(i *Interpreter) {
+		i.runtime = rt
+	}
+}
@@ -0,0 +1,175 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/lexer"
+	"github.com/polyscone/knight/parser"
+	"github.com/polyscone/knight/value"
+)
+
+func TestWithMaxSteps(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name     string
+		source   string
+		maxSteps int
+		wantErr  bool
+	}{
+		{"infinite loop", "WHILE T 1", 1000, true},
+		{"infinite recursion through eval", `; = f "EVAL f" : EVAL f`, 1000, true},
+		{"within budget", "+ 1 2", 3, false},
+		{"one step over budget", "+ 1 2", 2, true},
+		{"no limit", `; = i 0 : WHILE < i 1000 = i + i 1`, 0, false},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := lexer.New()
+			p := parser.New(l)
+			g := value.NewGlobalStore()
+			program, err := p.Parse(g, strings.NewReader(tc.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = interpreter.New(g, p, interpreter.WithMaxSteps(tc.maxSteps)).Execute(program)
+			if got := errors.Is(err, interpreter.ErrBudgetExceeded); got != tc.wantErr {
+				t.Errorf("want budget exceeded %v, got error %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestWithMaxDepth(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name     string
+		source   string
+		maxDepth int
+		wantErr  bool
+	}{
+		{"recursion over limit", `; = n 200 ; = f BLOCK IF n (; = n - n 1 + 1 CALL f) 0 : CALL f`, 100, true},
+		{"recursion within limit", `; = n 50 ; = f BLOCK IF n (; = n - n 1 + 1 CALL f) 0 : CALL f`, 100, false},
+		{"recursion through variables", `; = n 200 ; = f BLOCK IF n (; = n - n 1 + 1 f) 0 : f`, 100, true},
+		{"infinite recursion through eval", `; = f "EVAL f" : EVAL f`, 100, true},
+		{"tail recursion", `; = n 200000 ; = f BLOCK IF n (; = n - n 1 CALL f) 0 : CALL f`, 10, false},
+		{"tail recursion through variables", `; = n 200000 ; = f BLOCK IF n (; = n - n 1 f) 0 : f`, 10, false},
+		{"tail recursion through and", `; = n 200000 ; = f BLOCK & n (; = n - n 1 f) : f`, 10, false},
+		{"mutual tail recursion", `; = n 200000 ; = odd BLOCK IF n (; = n - n 1 even) F ; = even BLOCK IF n (; = n - n 1 odd) T : even`, 10, false},
+		{"no limit", `; = n 1000 ; = f BLOCK IF n (; = n - n 1 + 1 CALL f) 0 : CALL f`, 0, false},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := lexer.New()
+			p := parser.New(l)
+			g := value.NewGlobalStore()
+			program, err := p.Parse(g, strings.NewReader(tc.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = interpreter.New(g, p, interpreter.WithMaxDepth(tc.maxDepth)).Execute(program)
+			if got := errors.Is(err, interpreter.ErrMaxDepthExceeded); got != tc.wantErr {
+				t.Errorf("want max depth exceeded %v, got error %v", tc.wantErr, err)
+			}
+
+			if err != nil && !tc.wantErr {
+				t.Errorf("want no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWithStdio(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		source string
+		input  string
+		want   string
+	}{
+		{"output", `OUTPUT "foo"`, "", "foo\n"},
+		{"dump", "DUMP 123", "", "Number(123)"},
+		{"prompt", "OUTPUT + 'got ' PROMPT", "foo\nbar\n", "got foo\n"},
+		{"prompt twice", "; = a PROMPT ; = b PROMPT : OUTPUT + b a", "foo\nbar\n", "barfoo\n"},
+		{"output then error", `; OUTPUT "foo" : x`, "", "foo\n"},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := lexer.New()
+			p := parser.New(l)
+			g := value.NewGlobalStore()
+			program, err := p.Parse(g, strings.NewReader(tc.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var stdout bytes.Buffer
+			stdin := strings.NewReader(tc.input)
+			_, _ = interpreter.New(g, p, interpreter.WithStdin(stdin), interpreter.WithStdout(&stdout)).Execute(program)
+
+			if got := stdout.String(); got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+// promptRecorder records what had been written to a buffer at the time it was
+// first read from.
+type promptRecorder struct {
+	stdout  *bytes.Buffer
+	written string
+	read    bool
+}
+
+func (r *promptRecorder) Read(p []byte) (int, error) {
+	if !r.read {
+		r.written = r.stdout.String()
+		r.read = true
+	}
+
+	return copy(p, "foo\n"), io.EOF
+}
+
+func TestPromptFlushesStdout(t *testing.T) {
+	t.Parallel()
+
+	l := lexer.New()
+	p := parser.New(l)
+	g := value.NewGlobalStore()
+	program, err := p.Parse(g, strings.NewReader(`; OUTPUT "Enter a value: \" : PROMPT`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	stdin := &promptRecorder{stdout: &stdout}
+	if _, err := interpreter.New(g, p, interpreter.WithStdin(stdin), interpreter.WithStdout(&stdout)).Execute(program); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "Enter a value: "; stdin.written != want {
+		t.Errorf("want %q written before prompting, got %q", want, stdin.written)
+	}
+}
@@ -9,7 +9,12 @@ import (
 // the RHS expression if the LHS value was falsey.
 // This means that when the LHS value is true it short-circuits.
 func (i *Interpreter) Or(lhs value.Value, rhs ast.Node) (value.Value, error) {
-	if lhs.AsBool().Value {
+	b, err := value.ToBool(lhs)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.Value {
 		return lhs, nil
 	}
 
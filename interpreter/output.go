@@ -11,7 +11,12 @@ import (
 // If the given value ends with a backslash (\) then the backslash is omitted
 // and no newline is printed, otherwise a newline is printed with the value.
 func (i *Interpreter) Output(w io.Writer, val value.Value) (value.Value, error) {
-	out := val.AsString().Value
+	s, err := i.heap.ToString(val)
+	if err != nil {
+		return nil, err
+	}
+
+	out := s.Value()
 
 	if out != "" && out[len(out)-1] == '\\' {
 		fmt.Fprint(w, out[:len(out)-1])
@@ -0,0 +1,133 @@
+package interpreter
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/polyscone/knight/ast"
+	"github.com/polyscone/knight/internal/gencache"
+	"github.com/polyscone/knight/value"
+)
+
+// DefaultProgramCacheLimit is the maximum number of programs that a Runtime
+// caches for EVAL unless it's changed with SetProgramCacheLimit.
+const DefaultProgramCacheLimit = 1024
+
+// ProgramCacheStats describes the size and effectiveness of a runtime's EVAL
+// program cache.
+// Every miss is followed by a parse, or by a backend compiling code, and
+// Errors counts the parses that failed.
+type ProgramCacheStats struct {
+	Len       int
+	Limit     int
+	Hits      uint64
+	Misses    uint64
+	Errors    uint64
+	Evictions uint64
+}
+
+// programKey identifies a cached program by its source code.
+// Parsed programs refer directly to the globals they were parsed with, so the
+// store has to be part of the key for interpreters that share a runtime to
+// avoid sharing programs.
+type programKey struct {
+	globals *value.GlobalStore
+	source  string
+}
+
+// compiledKey identifies the code that a backend has compiled for a node with
+// Interpreter.Compile.
+type compiledKey struct {
+	node ast.Node
+}
+
+// programCache holds the programs that have been parsed by EVAL, and the code
+// that backends have compiled.
+//
+// Like the value package's interning caches it's bounded, and it evicts its
+// least recently used entries generationally.
+// Parsing is done without holding the cache's lock, so failed parses can't
+// leave it locked, and interpreters that share a runtime can parse at the same
+// time.
+type programCache struct {
+	cache *gencache.Cache
+
+	// errors must only be accessed atomically.
+	errors uint64
+}
+
+// evalProgram is a program that has been parsed by EVAL, along with anything
+// that a backend has compiled it to.
+type evalProgram struct {
+	program ast.Program
+	code    compiled
+}
+
+// compiled is code that a backend has compiled, which is only compiled by the
+// first caller that needs it.
+type compiled struct {
+	once  sync.Once
+	value interface{}
+	err   error
+}
+
+// get returns the compiled code, calling compile to compile it if it hasn't
+// been compiled yet.
+func (c *compiled) get(compile func() (interface{}, error)) (interface{}, error) {
+	c.once.Do(func() {
+		c.value, c.err = compile()
+	})
+
+	return c.value, c.err
+}
+
+// load returns the cached program for the given key, if there is one.
+func (c *programCache) load(key programKey) (*evalProgram, bool) {
+	entry, ok := c.cache.Load(gencache.Key{Other: key})
+	if !ok {
+		return nil, false
+	}
+
+	//nolint:forcetypeassert // the cache only holds programs
+	return entry.(*evalProgram), true
+}
+
+// store caches the given program and returns it, unless a program was cached
+// for the same key while it was being parsed, in which case that program is
+// returned instead so that every caller gets the same one.
+func (c *programCache) store(key programKey, program ast.Program) *evalProgram {
+	//nolint:forcetypeassert // the cache only holds programs
+	return c.cache.Store(gencache.Key{Other: key}, &evalProgram{program: program}).(*evalProgram)
+}
+
+// compile returns the code that was compiled for the given node, calling
+// compile to compile it if it hasn't been compiled yet.
+// Compiling is done without holding the cache's lock.
+func (c *programCache) compile(node ast.Node, compile func(ast.Node) (interface{}, error)) (interface{}, error) {
+	//nolint:forcetypeassert // the cache only holds compiled code for nodes
+	entry := c.cache.LoadOrCreate(gencache.Key{Other: compiledKey{node}}, func() interface{} {
+		return &compiled{}
+	}).(*compiled)
+
+	return entry.get(func() (interface{}, error) {
+		return compile(node)
+	})
+}
+
+// fail records a failed parse.
+func (c *programCache) fail() {
+	atomic.AddUint64(&c.errors, 1)
+}
+
+func (c *programCache) stats() ProgramCacheStats {
+	stats := c.cache.Stats()
+
+	return ProgramCacheStats{
+		Len:       stats.Len,
+		Limit:     stats.Limit,
+		Hits:      stats.Hits,
+		Misses:    stats.Misses,
+		Errors:    atomic.LoadUint64(&c.errors),
+		Evictions: stats.Evictions,
+	}
+}
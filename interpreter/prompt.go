@@ -17,5 +17,5 @@ func (i *Interpreter) Prompt(r StringReader) (value.Value, error) {
 		return nil, err
 	}
 
-	return value.NewString(strings.TrimRight(text, "\r\n")), nil
+	return i.heap.NewString(strings.TrimRight(text, "\r\n")), nil
 }
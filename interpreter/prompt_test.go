@@ -31,7 +31,7 @@ func TestPrompt(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			if got := result.AsString().Value; got != tc.want {
+			if got := result.AsString().Value(); got != tc.want {
 				t.Errorf("want %v, got %v", tc.want, got)
 			}
 		})
@@ -1,14 +1,35 @@
 package interpreter
 
 import (
-	"os"
+	"fmt"
 
 	"github.com/polyscone/knight/value"
 )
 
-// Quit will exit the program with the integer conversion of the given value.
+// ExitError is returned by Execute when a program calls QUIT.
+// It isn't a failure as such, but it's returned as an error so that execution
+// unwinds immediately and the host can decide what to do with the exit code.
+type ExitError struct {
+	Code int
+}
+
+// Error returns a description of the exit code.
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %v", e.Code)
+}
+
+// Quit stops the program with the integer conversion of the given value as its
+// exit code by returning an ExitError.
+// In strict mode the exit code must be between 0 and 127.
 func (i *Interpreter) Quit(val value.Value) (value.Value, error) {
-	os.Exit(val.AsInt().Value)
+	code, err := i.toInt(val)
+	if err != nil {
+		return nil, err
+	}
+
+	if i.strict && (code.Value < 0 || code.Value > 127) {
+		return nil, undefined("QUIT exit code %v is not between 0 and 127", code)
+	}
 
-	return nil, nil
+	return nil, &ExitError{Code: code.Value}
 }
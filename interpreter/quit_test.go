@@ -0,0 +1,61 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/lexer"
+	"github.com/polyscone/knight/parser"
+	"github.com/polyscone/knight/value"
+)
+
+func TestQuit(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name       string
+		source     string
+		wantCode   int
+		wantOutput string
+	}{
+		{"quit zero", "QUIT 0", 0, ""},
+		{"quit string code", `QUIT "3"`, 3, ""},
+		{"quit after output", `; OUTPUT "foo" ; QUIT 1 : OUTPUT "bar"`, 1, "foo\n"},
+		{"quit inside block", `; = f BLOCK QUIT 2 ; CALL f : OUTPUT "bar"`, 2, ""},
+		{"quit inside eval", `; EVAL "QUIT 4" : OUTPUT "bar"`, 4, ""},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := lexer.New()
+			p := parser.New(l)
+			g := value.NewGlobalStore()
+			program, err := p.Parse(g, strings.NewReader(tc.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var stdout bytes.Buffer
+			_, err = interpreter.New(g, p, interpreter.WithStdout(&stdout)).Execute(program)
+
+			var exit *interpreter.ExitError
+			if !errors.As(err, &exit) {
+				t.Fatalf("want *interpreter.ExitError, got %#v", err)
+			}
+
+			if exit.Code != tc.wantCode {
+				t.Errorf("want exit code %v, got %v", tc.wantCode, exit.Code)
+			}
+
+			if got := stdout.String(); got != tc.wantOutput {
+				t.Errorf("want output %q, got %q", tc.wantOutput, got)
+			}
+		})
+	}
+}
@@ -1,13 +1,32 @@
 package interpreter
 
-import (
-	"math/rand"
-
-	"github.com/polyscone/knight/value"
-)
+import "github.com/polyscone/knight/value"
 
 // Random returns a pseudo-random integer value.
+// Values only go up to the largest 32-bit integer with IntWrap32 semantics or
+// in strict mode.
 func (i *Interpreter) Random() (value.Value, error) {
-	//nolint:gosec // there's no need for a CSPRNG here
-	return value.NewInt(int(rand.Int63())), nil
+	i.randomUsed = true
+
+	if i.ints == IntWrap32 || i.strict {
+		return i.heap.NewInt(int(i.rand.Int31())), nil
+	}
+
+	return i.heap.NewInt(int(i.rand.Int63())), nil
+}
+
+// Seed returns the seed that was used to initialise the interpreter's source
+// of pseudo-random numbers, so that a run can be replayed with WithSeed.
+//
+// The seed is only known if the interpreter created its own source, which is
+// the case unless WithRandSource was used.
+func (i *Interpreter) Seed() (int64, bool) {
+	return i.seed, i.seedKnown
+}
+
+// RandomUsed reports whether RANDOM has drawn from the interpreter's source of
+// pseudo-random numbers, which is the only time the seed is needed to replay a
+// run.
+func (i *Interpreter) RandomUsed() bool {
+	return i.randomUsed
 }
@@ -0,0 +1,87 @@
+package interpreter_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/value"
+)
+
+func TestRandom(t *testing.T) {
+	t.Parallel()
+
+	t.Run("same seed gives the same sequence", func(t *testing.T) {
+		t.Parallel()
+
+		a := interpreter.New(nil, nil, interpreter.WithSeed(42))
+		b := interpreter.New(nil, nil, interpreter.WithSeed(42))
+
+		for n := 0; n < 10; n++ {
+			x, err := a.Random()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			y, err := b.Random()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !value.Equal(x, y) {
+				t.Fatalf("call %v: want %v, got %v", n, x, y)
+			}
+
+			if x.AsInt().Value < 0 {
+				t.Errorf("call %v: want non-negative value, got %v", n, x)
+			}
+		}
+	})
+
+	t.Run("seed is recorded", func(t *testing.T) {
+		t.Parallel()
+
+		if seed, ok := interpreter.New(nil, nil, interpreter.WithSeed(42)).Seed(); !ok || seed != 42 {
+			t.Errorf("want seed 42, got %v (known %v)", seed, ok)
+		}
+
+		if _, ok := interpreter.New(nil, nil).Seed(); !ok {
+			t.Error("want default seed to be known")
+		}
+
+		if _, ok := interpreter.New(nil, nil, interpreter.WithRandSource(rand.NewSource(1))).Seed(); ok {
+			t.Error("want seed of a custom source to be unknown")
+		}
+	})
+
+	t.Run("random use is recorded", func(t *testing.T) {
+		t.Parallel()
+
+		i := interpreter.New(nil, nil, interpreter.WithSeed(42))
+		if i.RandomUsed() {
+			t.Error("want random to be unused before RANDOM is called")
+		}
+
+		if _, err := i.Random(); err != nil {
+			t.Fatal(err)
+		}
+
+		if !i.RandomUsed() {
+			t.Error("want random to be used after RANDOM is called")
+		}
+	})
+
+	t.Run("default seed replays", func(t *testing.T) {
+		t.Parallel()
+
+		a := interpreter.New(nil, nil)
+		seed, _ := a.Seed()
+		b := interpreter.New(nil, nil, interpreter.WithSeed(seed))
+
+		x, _ := a.Random()
+		y, _ := b.Random()
+		if !value.Equal(x, y) {
+			t.Errorf("want %v, got %v", x, y)
+		}
+	})
+}
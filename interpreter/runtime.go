@@ -0,0 +1,71 @@
+package interpreter
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"github.com/polyscone/knight/internal/gencache"
+	"github.com/polyscone/knight/value"
+)
+
+// Runtime holds the state that belongs to a running Knight program rather than
+// to the code that runs it: the heap that its values are interned in, the
+// programs that have been parsed by EVAL or compiled by a backend, and the
+// reader that PROMPT reads from.
+//
+// Every interpreter has its own Runtime unless one is given with WithRuntime,
+// so interpreters that run at the same time don't contend on any shared state,
+// and all of a program's memory is released along with its interpreter.
+//
+// A Runtime can be shared by interpreters running on different goroutines,
+// except in reckless builds, which don't synchronise its caches.
+type Runtime struct {
+	heap     *value.Heap
+	stdin    *bufio.Reader
+	programs programCache
+}
+
+// NewRuntime returns a Runtime with a new heap and no cached programs that
+// PROMPT reads lines from the given reader with.
+// If the reader is nil then os.Stdin is used.
+func NewRuntime(stdin io.Reader) *Runtime {
+	if stdin == nil {
+		stdin = os.Stdin
+	}
+
+	return &Runtime{
+		heap:     value.NewHeap(),
+		stdin:    bufio.NewReader(stdin),
+		programs: programCache{cache: gencache.New(DefaultProgramCacheLimit)},
+	}
+}
+
+// Heap returns the heap that the runtime's values are interned in.
+// Parsers that are used with the runtime should be created with
+// parser.WithHeap so that literals are interned in the same heap.
+func (rt *Runtime) Heap() *value.Heap {
+	return rt.heap
+}
+
+// SetProgramCacheLimit sets the maximum number of programs parsed by EVAL or
+// compiled by a backend that the runtime caches, evicting programs straight
+// away if it's already over the new limit.
+// Limits are rounded up to an even number, and a limit of 0 disables the cache
+// entirely, which means every EVAL parses its program again.
+func (rt *Runtime) SetProgramCacheLimit(limit int) {
+	rt.programs.cache.SetLimit(limit)
+}
+
+// ProgramStats returns the current stats for the runtime's EVAL program cache.
+func (rt *Runtime) ProgramStats() ProgramCacheStats {
+	return rt.programs.stats()
+}
+
+// Reset discards every program that has been cached by EVAL or a backend and
+// clears the runtime's heap, releasing the memory they held.
+// Values that have already been created remain valid.
+func (rt *Runtime) Reset() {
+	rt.programs.cache.Clear()
+	rt.heap.Clear()
+}
@@ -0,0 +1,145 @@
+package interpreter_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/lexer"
+	"github.com/polyscone/knight/options"
+	"github.com/polyscone/knight/parser"
+	"github.com/polyscone/knight/value"
+)
+
+func TestRuntimeIsolation(t *testing.T) {
+	t.Parallel()
+
+	a := interpreter.New(value.NewGlobalStore(), parser.New(lexer.New()))
+	b := interpreter.New(value.NewGlobalStore(), parser.New(lexer.New()))
+
+	if a.Runtime() == b.Runtime() {
+		t.Fatal("want each interpreter to have its own runtime")
+	}
+
+	x, err := a.Add(value.NewString("foo"), value.NewString("bar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	y, err := b.Add(value.NewString("foo"), value.NewString("bar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if x == y {
+		t.Errorf("want values from different runtimes to be distinct, got %p twice", x)
+	}
+
+	if want := a.Runtime().Heap().NewString("foobar"); x != want {
+		t.Errorf("want %p from the interpreter's heap, got %p", want, x)
+	}
+
+	src := value.NewString("+ 1 2")
+	pa, err := a.ParseEval(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := b.Runtime().Heap().Stats()[value.CacheIntStrings].Misses; got != 0 {
+		t.Errorf("want no activity in the other runtime's heap, got %v misses", got)
+	}
+
+	pb, err := b.ParseEval(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pa.Root == pb.Root {
+		t.Error("want each runtime to parse its own programs")
+	}
+}
+
+func TestRuntimeShared(t *testing.T) {
+	t.Parallel()
+
+	if options.Reckless {
+		t.Skip("reckless builds don't synchronise runtimes")
+	}
+
+	rt := interpreter.NewRuntime(strings.NewReader("foo\nbar\n"))
+	g := value.NewGlobalStore()
+
+	var wg sync.WaitGroup
+	programs := make([]interface{}, 4)
+	for n := range programs {
+		n := n
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			p := parser.New(lexer.New(), parser.WithHeap(rt.Heap()))
+			i := interpreter.New(g, p, interpreter.WithRuntime(rt))
+
+			program, err := i.ParseEval(value.NewString("+ 1 2"))
+			if err != nil {
+				t.Error(err)
+
+				return
+			}
+
+			programs[n] = program.Root
+		}()
+	}
+	wg.Wait()
+
+	for _, root := range programs[1:] {
+		if root != programs[0] {
+			t.Errorf("want interpreters that share a runtime to share programs, got %v and %v", programs[0], root)
+		}
+	}
+
+	// Interpreters that share a runtime also share its stdin reader
+	for _, want := range []string{"foo", "bar"} {
+		i := interpreter.New(g, nil, interpreter.WithRuntime(rt))
+		got, err := i.Prompt(i.Stdin())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !value.Equal(got, value.NewString(want)) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	}
+}
+
+func TestRuntimeReset(t *testing.T) {
+	t.Parallel()
+
+	rt := interpreter.NewRuntime(nil)
+	i := interpreter.New(value.NewGlobalStore(), parser.New(lexer.New(), parser.WithHeap(rt.Heap())), interpreter.WithRuntime(rt))
+
+	src := value.NewString(`+ "a" "b"`)
+	before, err := i.ParseEval(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt.Reset()
+
+	for _, stats := range rt.Heap().Stats() {
+		if stats.Len != 0 {
+			t.Errorf("want the %v cache to be empty, got %v entries", stats.Cache, stats.Len)
+		}
+	}
+
+	after, err := i.ParseEval(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if before.Root == after.Root {
+		t.Error("want programs to be parsed again after a reset")
+	}
+}
@@ -0,0 +1,74 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/polyscone/knight/value"
+)
+
+// ErrUndefined is wrapped by the errors that are returned in strict mode when
+// a program does something that the Knight spec leaves undefined.
+var ErrUndefined = errors.New("undefined behaviour")
+
+// Strict reports whether the interpreter was created in strict mode with
+// WithStrict.
+func (i *Interpreter) Strict() bool {
+	return i.strict
+}
+
+// undefined returns an error that wraps ErrUndefined with the given message.
+func undefined(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %v", ErrUndefined, fmt.Sprintf(format, args...))
+}
+
+// checkNoBlocks returns an error in strict mode if the given value is a block
+// or a list that contains one.
+// The name is the name of the function that was given the value.
+func (i *Interpreter) checkNoBlocks(name string, val value.Value) error {
+	if !i.strict || !hasBlock(val) {
+		return nil
+	}
+
+	return undefined("%v cannot be given a block, got %s", name, val)
+}
+
+// checkSequence returns an error in strict mode if the given value isn't a
+// string or a list.
+// The name is the name of the function that was given the value.
+func (i *Interpreter) checkSequence(name string, val value.Value) error {
+	if !i.strict {
+		return nil
+	}
+
+	switch val.(type) {
+	case *value.String, *value.List:
+		return nil
+	}
+
+	return undefined("%v can only be given a string or a list, got %s", name, val)
+}
+
+// hasBlock reports whether the given value is a block or a list that contains
+// one at any depth.
+func hasBlock(val value.Value) bool {
+	switch val := val.(type) {
+	case *value.Block:
+		return true
+	case *value.List:
+		for _, v := range val.Value {
+			if hasBlock(v) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// portable reports whether the given byte is in the set of characters that the
+// spec requires implementations to support, which is printable ASCII, tab,
+// newline, and carriage return.
+func portable(b int) bool {
+	return b == '\t' || b == '\n' || b == '\r' || (b >= ' ' && b <= '~')
+}
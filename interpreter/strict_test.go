@@ -0,0 +1,180 @@
+package interpreter_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/lexer"
+	"github.com/polyscone/knight/parser"
+	"github.com/polyscone/knight/value"
+)
+
+func TestWithStrict(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		source string
+	}{
+		{"call int", "CALL 1"},
+		{"call string", `CALL "foo"`},
+		{"call block result", "CALL CALL BLOCK 1"},
+		{"ascii unsupported code", "ASCII 7"},
+		{"ascii negative code", "ASCII - 0 1"},
+		{"ascii unsupported character", "ASCII ASCII 127"},
+		{"quit negative", "QUIT - 0 1"},
+		{"quit too large", "QUIT 128"},
+		{"dump block", "DUMP BLOCK 1"},
+		{"dump list with block", "DUMP , BLOCK 1"},
+		{"equal block lhs", "? BLOCK 1 1"},
+		{"equal block rhs", "? 1 BLOCK 1"},
+		{"equal list with block", "? , BLOCK 1 @"},
+		{"get int", "GET 123 0 1"},
+		{"get null", "GET NULL 0 0"},
+		{"substitute bool", `SET T 0 1 "x"`},
+		{"add overflow", "+ 2147483647 1"},
+		{"sub overflow", "- - 0 2147483647 2"},
+		{"mul overflow", "* 65536 65536"},
+		{"div overflow", "/ - - 0 2147483647 1 - 0 1"},
+		{"exp overflow", "^ 2 31"},
+		{"exp negative", "^ 2 - 0 1"},
+		{"mod negative", "% - 0 7 2"},
+		{"less null", "< NULL 1"},
+		{"greater null", "> NULL 1"},
+		{"less block", "< BLOCK 1 1"},
+		{"greater block", "> BLOCK 1 1"},
+		{"less list with null", "< , NULL , 1"},
+		{"big literal", "+ 0 99999999999999999999"},
+		{"literal beyond 32 bits", "OUTPUT 5000000000"},
+		{"string beyond 32 bits", `< 0 "5000000000"`},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := runStrict(t, tc.source, false); errors.Is(err, interpreter.ErrUndefined) {
+				t.Errorf("want no undefined behaviour error outside of strict mode, got %v", err)
+			}
+
+			if err := runStrict(t, tc.source, true); !errors.Is(err, interpreter.ErrUndefined) {
+				t.Errorf("want undefined behaviour error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWithStrictDefined(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		source string
+		want   value.Value
+	}{
+		{"call block", "CALL BLOCK 1", value.NewInt(1)},
+		{"call variable block", "; = f BLOCK + 1 2 : CALL f", value.NewInt(3)},
+		{"variable block isn't called", "; = f BLOCK 1 ; = g f : CALL g", value.NewInt(1)},
+		{"call block returning block", "CALL CALL BLOCK BLOCK 2", value.NewInt(2)},
+		{"ascii newline", "ASCII 10", value.NewString("\n")},
+		{"ascii tilde", `ASCII "~"`, value.NewInt(126)},
+		{"largest int", "+ 2147483646 1", value.NewInt(2147483647)},
+		{"smallest int", "- - 0 2147483647 1", value.NewInt(-2147483648)},
+		{"exp", "^ 2 30", value.NewInt(1073741824)},
+		{"mod", "% 7 2", value.NewInt(1)},
+		{"get string", `GET "abc" 1 1`, value.NewString("b")},
+		{"equal", "? , 1 , 1", value.NewBool(true)},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := lexer.New()
+			p := parser.New(l)
+			g := value.NewGlobalStore()
+			program, err := p.Parse(g, strings.NewReader(tc.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := interpreter.New(g, p, interpreter.WithStrict(true)).Execute(program)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !value.Equal(tc.want, got) {
+				t.Errorf("want %v (%p), got %v (%p)", tc.want, tc.want, got, got)
+			}
+		})
+	}
+}
+
+func TestWithStrictVariableBlock(t *testing.T) {
+	t.Parallel()
+
+	l := lexer.New()
+	p := parser.New(l)
+	g := value.NewGlobalStore()
+	program, err := p.Parse(g, strings.NewReader("; = f BLOCK 1 : + 1 f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The block isn't called, so it has to be converted to an integer
+	_, err = interpreter.New(g, p, interpreter.WithStrict(true)).Execute(program)
+
+	var cerr *value.ConversionError
+	if !errors.As(err, &cerr) {
+		t.Errorf("want a conversion error, got %v", err)
+	}
+}
+
+func TestWithStrictBigInts(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		source string
+	}{
+		{"exp negative", "^ 2 - 0 1"},
+		{"mod negative", "% - 0 7 2"},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := runStrict(t, tc.source, true, interpreter.WithIntSemantics(interpreter.IntBig))
+			if !errors.Is(err, interpreter.ErrUndefined) {
+				t.Errorf("want undefined behaviour error, got %v", err)
+			}
+		})
+	}
+}
+
+// runStrict runs the given source and returns its error, with strict mode
+// turned on or off, and any other options.
+func runStrict(t *testing.T, source string, strict bool, opts ...interpreter.Option) error {
+	t.Helper()
+
+	l := lexer.New()
+	p := parser.New(l)
+	g := value.NewGlobalStore()
+	program, err := p.Parse(g, strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts = append(opts, interpreter.WithStrict(strict), interpreter.WithStdout(io.Discard))
+	i := interpreter.New(g, p, opts...)
+	_, err = i.Execute(program)
+
+	return err
+}
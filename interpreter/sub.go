@@ -9,9 +9,15 @@ import (
 // Sub returns the result of the subtraction of the RHS value from the LHS value.
 // The LHS value must be an integer, and the RHS value will be converted to an
 // integer if it isn't one already.
+// The result overflows according to the interpreter's integer semantics.
 func (i *Interpreter) Sub(lhs, rhs value.Value) (value.Value, error) {
 	if lhs, ok := lhs.(*value.Int); ok {
-		return value.NewInt(lhs.Value - rhs.AsInt().Value), nil
+		rhs, err := i.toInt(rhs)
+		if err != nil {
+			return nil, err
+		}
+
+		return i.arith(opSub, lhs, rhs)
 	}
 
 	return nil, fmt.Errorf("cannot subtract %s from %s", rhs, lhs)
@@ -4,44 +4,87 @@ import "github.com/polyscone/knight/value"
 
 // Substitute returns a new string value where the given range is replaced with
 // the given replacement value.
+// If the value is a list then the result is a new list where the range is
+// replaced with the list conversion of the replacement value instead.
+//
+// The start and count values must describe a range that is within the bounds
+// of the string or list, otherwise an error is returned.
+// In strict mode the value must already be a string or a list.
 func (i *Interpreter) Substitute(strVal, startVal, countVal, replacementVal value.Value) (value.Value, error) {
-	start := startVal.AsInt().Value
-	count := countVal.AsInt().Value
-	replacement := replacementVal.AsString()
+	if err := i.checkSequence("SET", strVal); err != nil {
+		return nil, err
+	}
+
+	if list, ok := strVal.(*value.List); ok {
+		return i.substituteList(list, startVal, countVal, replacementVal)
+	}
+
+	str, start, count, err := i.rangeArgs(strVal, startVal, countVal)
+	if err != nil {
+		return nil, err
+	}
+
+	replacement, err := i.heap.ToString(replacementVal)
+	if err != nil {
+		return nil, err
+	}
 
-	if count == 0 && replacement.Value == "" {
-		return strVal, nil
+	if count == 0 && replacement.Len() == 0 {
+		return str, nil
 	}
 
-	str := strVal.AsString()
 	amount := start + count
 
-	if replacement.Value == "" {
+	if replacement.Len() == 0 {
 		if start == 0 {
-			if amount == len(str.Value) {
-				return value.NewString(""), nil
+			if amount == str.Len() {
+				return i.heap.NewString(""), nil
 			}
 
-			return value.NewSubString(str, amount, len(str.Value)), nil
+			return i.heap.NewSubString(str, amount, str.Len()), nil
 		}
 
 		if amount == 0 || start == amount {
 			return str, nil
 		}
 
-		if amount == len(str.Value) {
-			return value.NewSubString(str, 0, start), nil
+		if amount == str.Len() {
+			return i.heap.NewSubString(str, 0, start), nil
 		}
 
-		lhs := value.NewSubString(str, 0, start)
-		rhs := value.NewSubString(str, amount, len(str.Value))
+		lhs := i.heap.NewSubString(str, 0, start)
+		rhs := i.heap.NewSubString(str, amount, str.Len())
+
+		return i.heap.NewConcatString(lhs, rhs), nil
+	}
+
+	lhs := i.heap.NewSubString(str, 0, start)
+	lhs = i.heap.NewConcatString(lhs, replacement)
+	rhs := i.heap.NewSubString(str, amount, str.Len())
+
+	return i.heap.NewConcatString(lhs, rhs), nil
+}
+
+// substituteList implements Substitute for lists.
+func (i *Interpreter) substituteList(list *value.List, startVal, countVal, replacementVal value.Value) (value.Value, error) {
+	start, count, err := i.bounds("list", list.Len(), startVal, countVal)
+	if err != nil {
+		return nil, err
+	}
+
+	replacement, err := i.heap.ToList(replacementVal)
+	if err != nil {
+		return nil, err
+	}
 
-		return value.NewConcatString(lhs, rhs), nil
+	if count == 0 && replacement.Len() == 0 {
+		return list, nil
 	}
 
-	lhs := value.NewSubString(str, 0, start)
-	lhs = value.NewConcatString(lhs, replacement)
-	rhs := value.NewSubString(str, amount, len(str.Value))
+	values := make([]value.Value, 0, list.Len()-count+replacement.Len())
+	values = append(values, list.Value[:start]...)
+	values = append(values, replacement.Value...)
+	values = append(values, list.Value[start+count:]...)
 
-	return value.NewConcatString(lhs, rhs), nil
+	return value.NewList(values), nil
 }
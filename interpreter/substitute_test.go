@@ -43,3 +43,73 @@ func TestSubstitute(t *testing.T) {
 		})
 	}
 }
+
+func TestSubstituteErrors(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name    string
+		str     string
+		start   int
+		count   int
+		replace string
+	}{
+		{"negative start", "foo", -1, 1, "bar"},
+		{"negative count", "foo", 1, -1, "bar"},
+		{"start past the end", "foo", 4, 0, "bar"},
+		{"count past the end", "foo", 1, 3, ""},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			str := value.NewString(tc.str)
+			start := value.NewInt(tc.start)
+			count := value.NewInt(tc.count)
+			replace := value.NewString(tc.replace)
+			if _, err := interpreter.New(nil, nil).Substitute(str, start, count, replace); err == nil {
+				t.Error("want error, got nil")
+			}
+		})
+	}
+}
+
+func TestSubstituteList(t *testing.T) {
+	t.Parallel()
+
+	abc := list(value.NewString("a"), value.NewString("b"), value.NewString("c"))
+
+	tt := []struct {
+		name    string
+		start   int
+		count   int
+		replace value.Value
+		want    value.Value
+	}{
+		{"substitute nothing with nothing", 0, 0, value.NewList(nil), abc},
+		{"substitute nothing with a list", 0, 0, list(value.NewInt(1)), list(value.NewInt(1), value.NewString("a"), value.NewString("b"), value.NewString("c"))},
+		{"substitute middle value with a list", 1, 1, list(value.NewInt(1), value.NewInt(2)), list(value.NewString("a"), value.NewInt(1), value.NewInt(2), value.NewString("c"))},
+		{"remove last values", 1, 2, value.NewNull(), list(value.NewString("a"))},
+		{"replacement is converted", 3, 0, value.NewString("de"), list(value.NewString("a"), value.NewString("b"), value.NewString("c"), value.NewString("d"), value.NewString("e"))},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			start := value.NewInt(tc.start)
+			count := value.NewInt(tc.count)
+			result, err := interpreter.New(nil, nil).Substitute(abc, start, count, tc.replace)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !value.Equal(result, tc.want) {
+				t.Errorf("want %v (%p), got %v (%p)", tc.want, tc.want, result, result)
+			}
+		})
+	}
+}
@@ -1,16 +1,37 @@
 package interpreter
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os/exec"
 	"runtime"
+	"strings"
 
 	"github.com/polyscone/knight/value"
 )
 
-// System executes the string conversion of the given value.
+// ErrCommandDenied is returned by CommandRunners that refuse to run a command.
+var ErrCommandDenied = errors.New("command denied")
+
+// CommandRunner runs the commands given to SYSTEM and returns their standard
+// output.
+// Anything the command writes to standard error should be written to stderr.
+//
+// The command should be stopped if the given context is cancelled.
+type CommandRunner interface {
+	RunCommand(ctx context.Context, command string, stderr io.Writer) (string, error)
+}
+
+// ShellRunner runs commands through the system shell.
 //
 // Windows systems will use cmd, otherwise sh will be used instead.
-func (i *Interpreter) System(val value.Value) (value.Value, error) {
+type ShellRunner struct{}
+
+// RunCommand runs the given command through the system shell.
+func (ShellRunner) RunCommand(ctx context.Context, command string, stderr io.Writer) (string, error) {
 	var sh string
 	var args []string
 	if runtime.GOOS == "windows" {
@@ -21,12 +42,114 @@ func (i *Interpreter) System(val value.Value) (value.Value, error) {
 		args = append(args, "-c")
 	}
 
-	args = append(args, val.AsString().Value)
+	args = append(args, command)
+
+	return run(ctx, exec.CommandContext(ctx, sh, args...), stderr)
+}
+
+// DenyRunner refuses to run any command.
+type DenyRunner struct{}
+
+// RunCommand always returns ErrCommandDenied.
+func (DenyRunner) RunCommand(ctx context.Context, command string, stderr io.Writer) (string, error) {
+	return "", fmt.Errorf("%w: %q", ErrCommandDenied, command)
+}
+
+// AllowlistRunner only runs commands whose program name is in Names.
+//
+// Commands are split into fields on whitespace and run directly rather than
+// through a shell, so shell features like pipes, redirection, quoting, and
+// variable expansion are not available; this stops an allowed program from
+// being used to run one that isn't allowed.
+type AllowlistRunner struct {
+	Names []string
+}
+
+// RunCommand runs the given command if its program name is allowed, otherwise
+// it returns ErrCommandDenied.
+func (r AllowlistRunner) RunCommand(ctx context.Context, command string, stderr io.Writer) (string, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("%w: %q", ErrCommandDenied, command)
+	}
+
+	for _, name := range r.Names {
+		if fields[0] == name {
+			return run(ctx, exec.CommandContext(ctx, fields[0], fields[1:]...), stderr)
+		}
+	}
+
+	return "", fmt.Errorf("%w: %q", ErrCommandDenied, command)
+}
+
+// ScriptedRunner is a fake CommandRunner for tests that returns canned output
+// for known commands without running anything.
+type ScriptedRunner struct {
+	// Outputs maps each known command to the output it should produce.
+	// Commands that aren't in the map return ErrCommandDenied.
+	Outputs map[string]string
+
+	// Commands records every command that was requested, in order.
+	Commands []string
+}
+
+// RunCommand records the given command and returns its scripted output.
+func (r *ScriptedRunner) RunCommand(ctx context.Context, command string, stderr io.Writer) (string, error) {
+	r.Commands = append(r.Commands, command)
+
+	out, ok := r.Outputs[command]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrCommandDenied, command)
+	}
+
+	return out, nil
+}
+
+// run starts the given command and waits for it to finish, returning its
+// standard output.
+func run(ctx context.Context, c *exec.Cmd, stderr io.Writer) (string, error) {
+	var out bytes.Buffer
+	c.Stdout = &out
+	c.Stderr = stderr
+
+	if err := c.Start(); err != nil {
+		return "", err
+	}
+
+	// Killing the process doesn't kill any children it started, and if they
+	// still hold its stdout open then Wait won't return until they exit, so
+	// when cancelled we return straight away and let Wait finish in the
+	// background
+	waited := make(chan error, 1)
+	go func() { waited <- c.Wait() }()
+
+	select {
+	case err := <-waited:
+		if err != nil {
+			return "", err
+		}
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	return out.String(), nil
+}
+
+// System runs the string conversion of the given value using the interpreter's
+// CommandRunner and returns its output.
+// By default commands are run through the system shell.
+//
+// The command is stopped if execution is cancelled while it's running.
+func (i *Interpreter) System(val value.Value) (value.Value, error) {
+	cmd, err := i.heap.ToString(val)
+	if err != nil {
+		return nil, err
+	}
 
-	out, err := exec.Command(sh, args...).Output()
+	out, err := i.runner.RunCommand(i.ctx, cmd.Value(), i.stderr)
 	if err != nil {
 		return nil, err
 	}
 
-	return value.NewString(string(out)), nil
+	return i.heap.NewString(out), nil
 }
@@ -0,0 +1,90 @@
+package interpreter_test
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/lexer"
+	"github.com/polyscone/knight/parser"
+	"github.com/polyscone/knight/value"
+)
+
+func TestSystem(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name    string
+		command string
+		runner  interpreter.CommandRunner
+		want    string
+		wantErr error
+		unix    bool
+	}{
+		{"shell", "echo foo | tr o a", interpreter.ShellRunner{}, "faa\n", nil, true},
+		{"deny", "echo foo", interpreter.DenyRunner{}, "", interpreter.ErrCommandDenied, false},
+		{"allowlist allowed", "echo foo  bar", interpreter.AllowlistRunner{Names: []string{"echo"}}, "foo bar\n", nil, true},
+		{"allowlist denied", "ls", interpreter.AllowlistRunner{Names: []string{"echo"}}, "", interpreter.ErrCommandDenied, false},
+		{"allowlist empty", "  ", interpreter.AllowlistRunner{Names: []string{"echo"}}, "", interpreter.ErrCommandDenied, false},
+		{"allowlist has no shell", "echo foo; ls", interpreter.AllowlistRunner{Names: []string{"echo"}}, "foo; ls\n", nil, true},
+		{"scripted known", "date", &interpreter.ScriptedRunner{Outputs: map[string]string{"date": "today\n"}}, "today\n", nil, false},
+		{"scripted unknown", "ls", &interpreter.ScriptedRunner{Outputs: map[string]string{"date": "today\n"}}, "", interpreter.ErrCommandDenied, false},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.unix && runtime.GOOS == "windows" {
+				t.Skip("requires a unix system")
+			}
+
+			result, err := interpreter.New(nil, nil, interpreter.WithCommandRunner(tc.runner)).System(value.NewString(tc.command))
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("want error %v, got %v", tc.wantErr, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if got := result.AsString().Value(); got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSystemScripted(t *testing.T) {
+	t.Parallel()
+
+	l := lexer.New()
+	p := parser.New(l)
+	g := value.NewGlobalStore()
+	program, err := p.Parse(g, strings.NewReader("+ ` 'cat foo.kn' ` 'cat bar.kn'"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner := &interpreter.ScriptedRunner{
+		Outputs: map[string]string{
+			"cat foo.kn": "foo",
+			"cat bar.kn": "bar",
+		},
+	}
+	result, err := interpreter.New(g, p, interpreter.WithCommandRunner(runner)).Execute(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := value.NewString("foobar"); !value.Equal(result, want) {
+		t.Errorf("want %v, got %v", want, result)
+	}
+
+	if want, got := "cat foo.kn, cat bar.kn", strings.Join(runner.Commands, ", "); got != want {
+		t.Errorf("want commands %q, got %q", want, got)
+	}
+}
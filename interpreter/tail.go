@@ -0,0 +1,29 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/polyscone/knight/value"
+)
+
+// Tail returns every value in the given list after the first one, or every
+// byte of the given string after the first one.
+// Empty lists and strings don't have a tail, so they result in an error.
+func (i *Interpreter) Tail(val value.Value) (value.Value, error) {
+	switch val := val.(type) {
+	case *value.List:
+		if val.Len() == 0 {
+			return nil, fmt.Errorf("cannot take the tail of an empty list")
+		}
+
+		return value.NewList(val.Value[1:]), nil
+	case *value.String:
+		if val.Len() == 0 {
+			return nil, fmt.Errorf("cannot take the tail of an empty string")
+		}
+
+		return i.heap.NewSubString(val, 1, val.Len()), nil
+	}
+
+	return nil, fmt.Errorf("cannot take the tail of %s", val)
+}
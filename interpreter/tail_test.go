@@ -0,0 +1,63 @@
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/value"
+)
+
+func TestTail(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name  string
+		value value.Value
+		want  value.Value
+	}{
+		{"list", list(value.NewInt(1), value.NewInt(2), value.NewInt(3)), list(value.NewInt(2), value.NewInt(3))},
+		{"string", value.NewString("foo"), value.NewString("oo")},
+		{"single nested list", list(list(value.NewInt(2))), value.NewList(nil)},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := interpreter.New(nil, nil).Tail(tc.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !value.Equal(result, tc.want) {
+				t.Errorf("want %v (%p), got %v (%p)", tc.want, tc.want, result, result)
+			}
+		})
+	}
+}
+
+func TestTailErrors(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name  string
+		value value.Value
+	}{
+		{"empty list", value.NewList(nil)},
+		{"empty string", value.NewString("")},
+		{"int", value.NewInt(1)},
+		{"block", value.NewBlock(value.NewInt(1))},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := interpreter.New(nil, nil).Tail(tc.value); err == nil {
+				t.Error("want error, got nil")
+			}
+		})
+	}
+}
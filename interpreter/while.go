@@ -7,14 +7,24 @@ import (
 
 // While evaluates the given body expression for as long as the evaluation of
 // the condition expression results in a truthy value.
+// The loop stops early if execution is cancelled.
 func (i *Interpreter) While(condition, body ast.Node) (value.Value, error) {
 	for {
+		if err := i.Cancelled(); err != nil {
+			return nil, err
+		}
+
 		result, err := i.eval(condition)
 		if err != nil {
 			return nil, err
 		}
 
-		if !result.AsBool().Value {
+		b, err := value.ToBool(result)
+		if err != nil {
+			return nil, err
+		}
+
+		if !b.Value {
 			break
 		}
 
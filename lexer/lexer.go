@@ -10,9 +10,23 @@ import (
 
 const eof byte = 0
 
-// ErrUnknownToken is returned with every consumed token that isn't specified
-// by the language in some way.
-var ErrUnknownToken = errors.New("unknown token")
+// These errors are returned along with a token of kind token.Err or
+// token.Unknown when the source code is malformed in some way.
+// The returned token records the position the problem starts at.
+var (
+	// ErrUnknownToken is returned with every consumed token that isn't
+	// specified by the language in some way.
+	ErrUnknownToken = errors.New("unknown token")
+
+	// ErrUnterminatedString is returned when a string literal has no closing
+	// quote before the end of the source code.
+	// The returned token is positioned at the opening quote.
+	ErrUnterminatedString = errors.New("unterminated string")
+
+	// ErrInvalidByte is returned when the source code contains a NUL byte,
+	// which can never appear in a valid Knight program.
+	ErrInvalidByte = errors.New("invalid NUL byte")
+)
 
 type predicate func(byte) bool
 
@@ -35,7 +49,7 @@ type Lexer struct {
 // code in the given byte scanner.
 func (l *Lexer) Load(r io.ByteScanner) {
 	l.r = r
-	l.pos = position{line: 1, col: 0}
+	l.pos = position{line: 1, col: 1}
 	l.curr = token.Token{Kind: token.EOF}
 	l.next = token.Token{Kind: token.EOF}
 	l.err = nil
@@ -63,9 +77,8 @@ func (l *Lexer) Consume() (token.Token, error) {
 
 func (l *Lexer) consume() (token.Token, error) {
 start:
-	pos := l.pos
 	if _, err := l.readWhile(isWhitespace); err != nil {
-		return l.newError(err, pos)
+		return l.newError(err, l.pos)
 	}
 
 	r, err := l.peek()
@@ -74,15 +87,14 @@ start:
 	}
 
 	if isComment(r) {
-		pos := l.pos
 		if _, err := l.readWhile(notNewline); err != nil {
-			return l.newError(err, pos)
+			return l.newError(err, l.pos)
 		}
 
 		goto start
 	}
 
-	pos = l.pos
+	pos := l.pos
 	switch {
 	case isEOF(r):
 		return l.newToken(token.EOF, "", pos)
@@ -125,6 +137,14 @@ start:
 			return l.newToken(token.Chain, ";", pos)
 		case ':':
 			return l.newToken(token.Noop, ":", pos)
+		case '@':
+			return l.newToken(token.List, "@", pos)
+		case ',':
+			return l.newToken(token.Box, ",", pos)
+		case '[':
+			return l.newToken(token.Head, "[", pos)
+		case ']':
+			return l.newToken(token.Tail, "]", pos)
 		}
 
 		return l.newUnknown(string(lexeme), pos)
@@ -169,10 +189,15 @@ start:
 			return l.newError(err, pos)
 		}
 
-		// Discard close quote
-		if _, err := l.read(); err != nil {
+		// Discard close quote, which will only be missing if we hit the end
+		// of the source code first
+		closing, err := l.read()
+		if err != nil {
 			return l.newError(err, pos)
 		}
+		if closing != r {
+			return l.newError(ErrUnterminatedString, pos)
+		}
 
 		return l.newToken(token.String, lexeme, pos)
 	}
@@ -187,10 +212,12 @@ start:
 
 func (l *Lexer) newToken(kind token.Kind, lexeme string, pos position) (token.Token, error) {
 	tok := token.Token{
-		Kind:   kind,
-		Lexeme: lexeme,
-		Line:   pos.line,
-		Col:    pos.col,
+		Kind:    kind,
+		Lexeme:  lexeme,
+		Line:    pos.line,
+		Col:     pos.col,
+		EndLine: l.pos.line,
+		EndCol:  l.pos.col,
 	}
 
 	return tok, nil
@@ -208,6 +235,9 @@ func (l *Lexer) newError(err error, pos position) (token.Token, error) {
 	return tok, err
 }
 
+// peek and read both return the eof byte when there is no more input.
+// Since a NUL byte in the source is reported as an error rather than returned
+// the eof byte can never be confused with real input.
 func (l *Lexer) peek() (byte, error) {
 	r, err := l.r.ReadByte()
 	//nolint:errorlint // io.EOF is never wrapped
@@ -217,6 +247,9 @@ func (l *Lexer) peek() (byte, error) {
 	if err != nil {
 		return r, err
 	}
+	if r == eof {
+		return r, ErrInvalidByte
+	}
 
 	return r, l.r.UnreadByte()
 }
@@ -230,6 +263,9 @@ func (l *Lexer) read() (byte, error) {
 	if err != nil {
 		return r, err
 	}
+	if r == eof {
+		return r, ErrInvalidByte
+	}
 
 	l.lastPos = l.pos
 	if r == '\n' {
@@ -295,7 +331,7 @@ func notNewline(r byte) bool {
 
 func isWhitespace(r byte) bool {
 	switch r {
-	case ' ', '\t', '\n', '\r', ':', '(', ')', '{', '}', '[', ']':
+	case ' ', '\t', '\n', '\r', ':', '(', ')', '{', '}':
 		return true
 	}
 
@@ -312,7 +348,7 @@ func isEOF(r byte) bool {
 
 func isPunc(r byte) bool {
 	switch r {
-	case '!', '%', '&', '*', '+', '-', '/', ':', ';', '<', '=', '>', '?', '^', '`', '|':
+	case '!', '%', '&', '*', '+', ',', '-', '/', ':', ';', '<', '=', '>', '?', '@', '[', ']', '^', '`', '|':
 		return true
 	}
 
@@ -2,6 +2,7 @@ package lexer_test
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -73,6 +74,10 @@ func TestPuncTokens(t *testing.T) {
 		{"system (`)", "`", token.System},
 		{"chaining (;)", ";", token.Chain},
 		// {"no-op (:)", ":", token.Noop},
+		{"empty list (@)", "@", token.List},
+		{"box (,)", ",", token.Box},
+		{"head ([)", "[", token.Head},
+		{"tail (])", "]", token.Tail},
 	}
 	for _, tc := range tt {
 		tc := tc
@@ -296,6 +301,47 @@ func TestUnknownTokens(t *testing.T) {
 	}
 }
 
+func TestErrors(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name  string
+		input string
+		err   error
+		line  int
+		col   int
+	}{
+		{"unterminated double quote string", `"Hello, World!`, lexer.ErrUnterminatedString, 1, 1},
+		{"unterminated single quote string", `'Hello, World!`, lexer.ErrUnterminatedString, 1, 1},
+		{"unterminated empty string", `"`, lexer.ErrUnterminatedString, 1, 1},
+		{"unterminated string with mismatched quote", `"Hello, World!'`, lexer.ErrUnterminatedString, 1, 1},
+		{"unterminated string after whitespace", "\n  \t'Hello\nWorld!", lexer.ErrUnterminatedString, 2, 4},
+		{"nul byte", "\x00", lexer.ErrInvalidByte, 1, 1},
+		{"nul byte after whitespace", "\n  \x00", lexer.ErrInvalidByte, 2, 3},
+		{"nul byte in string", "'foo\x00bar'", lexer.ErrInvalidByte, 1, 1},
+		{"unknown token", "\n  .", lexer.ErrUnknownToken, 2, 3},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := lexer.New()
+			l.Load(strings.NewReader(tc.input))
+
+			tok, err := l.Consume()
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("want error %q, got %v", tc.err, err)
+			}
+
+			if tok.Line != tc.line || tok.Col != tc.col {
+				t.Errorf("want position %v:%v, got %v:%v", tc.line, tc.col, tok.Line, tok.Col)
+			}
+		})
+	}
+}
+
 func TestTokenStream(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,361 @@
+// Package optimize rewrites Knight programs into simpler programs that
+// produce the same results with less work.
+package optimize
+
+import (
+	"math"
+
+	"github.com/polyscone/knight/ast"
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/token"
+	"github.com/polyscone/knight/value"
+)
+
+// Level controls which optimisations are applied to a program.
+type Level int
+
+// Optimisation levels.
+const (
+	// None leaves the program untouched.
+	None Level = iota
+
+	// Simplify removes NOOP (:) wrappers, flattens chains (;) and drops any of
+	// their expressions that have no effect, and prunes IF, WHILE, AND, and OR
+	// expressions that have a constant condition.
+	Simplify
+
+	// Fold does everything that Simplify does, and also evaluates pure
+	// operations on literal operands ahead of time.
+	Fold
+)
+
+// maxFoldedString is the length of the longest string that string
+// multiplication will fold, so that optimising a program doesn't allocate
+// huge strings that might never be used.
+const maxFoldedString = 4096
+
+type optimizer struct {
+	level Level
+
+	// interp is only used to call pure builtin functions when folding, so
+	// that folded results always match the results at runtime.
+	// It's strict so that anything the spec leaves undefined, like integers
+	// that overflow 32 bits, is left for the program's own interpreter, whose
+	// integer semantics might be different.
+	interp *interpreter.Interpreter
+}
+
+// Program returns an optimised copy of the given program.
+// The original program isn't modified.
+//
+// Programs that are run by EVAL are parsed at runtime, so they aren't
+// optimised, but the bodies of blocks are.
+func Program(program ast.Program, level Level) ast.Program {
+	if level <= None {
+		return program
+	}
+
+	o := optimizer{
+		level:  level,
+		interp: interpreter.New(nil, nil, interpreter.WithStrict(true)),
+	}
+
+	return ast.Program{Root: o.optimize(program.Root)}
+}
+
+func (o *optimizer) optimize(node ast.Node) ast.Node {
+	switch v := node.(type) {
+	case *ast.Unary:
+		return o.unary(v)
+	case *ast.Binary:
+		return o.binary(v)
+	}
+
+	return o.builtin(node)
+}
+
+func (o *optimizer) unary(v *ast.Unary) ast.Node {
+	arg := o.optimize(v.Node)
+
+	switch v.Op {
+	case token.Noop:
+		return arg
+	case token.Not:
+		if vals, ok := o.fold(arg); ok {
+			if result, ok := folded(o.interp.Not(vals[0])); ok {
+				return result
+			}
+		}
+	}
+
+	return ast.NewUnary(v.Span, v.Op, arg)
+}
+
+func (o *optimizer) binary(v *ast.Binary) ast.Node {
+	if v.Op == token.Chain {
+		return o.chain(v)
+	}
+
+	lhs := o.optimize(v.LHS)
+	rhs := o.optimize(v.RHS)
+
+	switch v.Op {
+	case token.And, token.Or:
+		b, ok := truthy(lhs)
+		if !ok {
+			break
+		}
+
+		// AND returns its LHS when it's falsey and OR returns its LHS when
+		// it's truthy, otherwise they both evaluate to the RHS
+		if b == (v.Op == token.Or) {
+			return lhs
+		}
+
+		return rhs
+	case token.Add,
+		token.Sub,
+		token.Mul,
+		token.Div,
+		token.Mod,
+		token.Exp,
+		token.Less,
+		token.Greater,
+		token.Equal:
+
+		args, ok := o.fold(lhs, rhs)
+		if !ok {
+			break
+		}
+
+		if result, ok := o.binaryResult(v.Op, args[0], args[1]); ok {
+			return result
+		}
+	}
+
+	return ast.NewBinary(v.Span, v.Op, lhs, rhs)
+}
+
+func (o *optimizer) binaryResult(op token.Kind, lhs, rhs value.Value) (ast.Node, bool) {
+	switch op {
+	case token.Add:
+		return folded(o.interp.Add(lhs, rhs))
+	case token.Sub:
+		return folded(o.interp.Sub(lhs, rhs))
+	case token.Mul:
+		if s, ok := lhs.(*value.String); ok {
+			if n, ok := rhs.(*value.Int); !ok || s.Len()*n.Value > maxFoldedString {
+				return nil, false
+			}
+		}
+
+		return folded(o.interp.Mul(lhs, rhs))
+	case token.Div:
+		return folded(o.interp.Div(lhs, rhs))
+	case token.Mod:
+		return folded(o.interp.Mod(lhs, rhs))
+	case token.Exp:
+		return folded(o.interp.Exp(lhs, rhs))
+	case token.Less:
+		return folded(o.interp.Less(lhs, rhs))
+	case token.Greater:
+		return folded(o.interp.Greater(lhs, rhs))
+	case token.Equal:
+		return folded(o.interp.Equal(lhs, rhs))
+	}
+
+	return nil, false
+}
+
+// chain flattens a chain, however it's nested, into a right-nested sequence
+// and drops any expression before the last one that has no effect.
+func (o *optimizer) chain(v *ast.Binary) ast.Node {
+	var exprs []ast.Node
+	o.sequence(&exprs, v)
+
+	kept := exprs[:0]
+	for n, expr := range exprs {
+		if n == len(exprs)-1 || !pure(expr) {
+			kept = append(kept, expr)
+		}
+	}
+
+	result := kept[len(kept)-1]
+	for n := len(kept) - 2; n >= 0; n-- {
+		result = ast.NewBinary(v.Span, token.Chain, kept[n], result)
+	}
+
+	return result
+}
+
+// sequence appends the optimised expressions of a chain in evaluation order.
+func (o *optimizer) sequence(exprs *[]ast.Node, node ast.Node) {
+	if v, ok := node.(*ast.Binary); ok && v.Op == token.Chain {
+		o.sequence(exprs, v.LHS)
+		o.sequence(exprs, v.RHS)
+
+		return
+	}
+
+	expr := o.optimize(node)
+
+	// Optimising an expression might turn it into a chain, like when an IF
+	// is pruned to a branch that contains one, so it's flattened too
+	if v, ok := expr.(*ast.Binary); ok && v.Op == token.Chain {
+		o.sequence(exprs, v.LHS)
+		o.sequence(exprs, v.RHS)
+
+		return
+	}
+
+	*exprs = append(*exprs, expr)
+}
+
+func (o *optimizer) builtin(node ast.Node) ast.Node {
+	switch v := node.(type) {
+	case *ast.ASCII:
+		arg := o.optimize(v.Arg)
+		if vals, ok := o.fold(arg); ok {
+			if result, ok := folded(o.interp.ASCII(vals[0])); ok {
+				return result
+			}
+		}
+
+		return ast.NewASCII(v.Span, v.Name, arg)
+	case *ast.Block:
+		return ast.NewBlock(v.Span, v.Name, o.optimize(v.Body))
+	case *ast.CallBlock:
+		return ast.NewCallBlock(v.Span, v.Name, o.optimize(v.Block))
+	case *ast.Dump:
+		return ast.NewDump(v.Span, v.Name, o.optimize(v.Arg))
+	case *ast.Eval:
+		return ast.NewEval(v.Span, v.Name, o.optimize(v.Arg))
+	case *ast.Get:
+		str, start, count := o.optimize(v.Str), o.optimize(v.Start), o.optimize(v.Count)
+		if vals, ok := o.fold(str, start, count); ok {
+			if result, ok := folded(o.interp.Get(vals[0], vals[1], vals[2])); ok {
+				return result
+			}
+		}
+
+		return ast.NewGet(v.Span, v.Name, str, start, count)
+	case *ast.If:
+		condition := o.optimize(v.Condition)
+		if b, ok := truthy(condition); ok {
+			if b {
+				return o.optimize(v.Consequence)
+			}
+
+			return o.optimize(v.Alternative)
+		}
+
+		return ast.NewIf(v.Span, v.Name, condition, o.optimize(v.Consequence), o.optimize(v.Alternative))
+	case *ast.Length:
+		arg := o.optimize(v.Arg)
+		if vals, ok := o.fold(arg); ok {
+			if result, ok := folded(o.interp.Length(vals[0])); ok {
+				return result
+			}
+		}
+
+		return ast.NewLength(v.Span, v.Name, arg)
+	case *ast.Output:
+		return ast.NewOutput(v.Span, v.Name, o.optimize(v.Arg))
+	case *ast.Quit:
+		return ast.NewQuit(v.Span, v.Name, o.optimize(v.Arg))
+	case *ast.Substitute:
+		str, start, count, replacement := o.optimize(v.Str), o.optimize(v.Start), o.optimize(v.Count), o.optimize(v.Replacement)
+		if vals, ok := o.fold(str, start, count, replacement); ok {
+			if result, ok := folded(o.interp.Substitute(vals[0], vals[1], vals[2], vals[3])); ok {
+				return result
+			}
+		}
+
+		return ast.NewSubstitute(v.Span, v.Name, str, start, count, replacement)
+	case *ast.While:
+		condition := o.optimize(v.Condition)
+		if b, ok := truthy(condition); ok && !b {
+			return value.NewNull()
+		}
+
+		return ast.NewWhile(v.Span, v.Name, condition, o.optimize(v.Body))
+	}
+
+	// Anything else, like PROMPT and RANDOM, has no arguments to optimise
+	return node
+}
+
+// fold returns the values of the given nodes if folding is enabled and every
+// node is a literal.
+func (o *optimizer) fold(nodes ...ast.Node) ([]value.Value, bool) {
+	if o.level < Fold {
+		return nil, false
+	}
+
+	vals := make([]value.Value, len(nodes))
+	for n, node := range nodes {
+		val, ok := literal(node)
+		if !ok {
+			return nil, false
+		}
+
+		vals[n] = val
+	}
+
+	return vals, true
+}
+
+// folded returns the result of a folded builtin as a node.
+// Anything that fails is left for the program to report at runtime instead.
+func folded(val value.Value, err error) (ast.Node, bool) {
+	if err != nil {
+		return nil, false
+	}
+
+	return val, true
+}
+
+// literal returns the value of the given node if it's a literal.
+// Integers outside of the 32-bit range aren't treated as literals, because
+// their value depends on the integer semantics of the program's interpreter.
+func literal(node ast.Node) (value.Value, bool) {
+	switch v := node.(type) {
+	case *value.Int:
+		if v.IsBig() || v.Value < math.MinInt32 || v.Value > math.MaxInt32 {
+			return nil, false
+		}
+
+		return v, true
+	case *value.String:
+		return v, true
+	case *value.Bool:
+		return v, true
+	case *value.Null:
+		return v, true
+	}
+
+	return nil, false
+}
+
+// truthy returns the boolean value of the given node if it's a literal.
+func truthy(node ast.Node) (bool, bool) {
+	val, ok := literal(node)
+	if !ok {
+		return false, false
+	}
+
+	return val.AsBool().Value, true
+}
+
+// pure reports whether evaluating the given node has no effect other than
+// producing its value, which means it can be dropped if the value is unused.
+// Variables aren't pure because reading them can fail or call a block.
+func pure(node ast.Node) bool {
+	if _, ok := literal(node); ok {
+		return true
+	}
+
+	_, ok := node.(*ast.Block)
+
+	return ok
+}
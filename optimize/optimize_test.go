@@ -0,0 +1,149 @@
+package optimize_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/polyscone/knight/ast"
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/lexer"
+	"github.com/polyscone/knight/optimize"
+	"github.com/polyscone/knight/parser"
+	"github.com/polyscone/knight/token"
+	"github.com/polyscone/knight/value"
+)
+
+func TestProgram(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		source string
+		level  optimize.Level
+		want   string
+	}{
+		{"none", "IF T + 1 2 0", optimize.None, "(program (IF true (add 1 2) 0))"},
+		{"simplify doesn't fold", "+ 1 2", optimize.Simplify, "(program (add 1 2))"},
+		{"if true", "IF T a b", optimize.Simplify, `(program (var "a"))`},
+		{"if false", `IF "" a b`, optimize.Simplify, `(program (var "b"))`},
+		{"if unknown", "IF c a b", optimize.Simplify, `(program (IF (var "c") (var "a") (var "b")))`},
+		{"while false", "WHILE F O 1", optimize.Simplify, "(program null)"},
+		{"while true", "WHILE T O 1", optimize.Simplify, "(program (WHILE true (O 1)))"},
+		{"and falsey", "& 0 a", optimize.Simplify, "(program 0)"},
+		{"and truthy", "& 1 a", optimize.Simplify, `(program (var "a"))`},
+		{"or falsey", "| NULL a", optimize.Simplify, `(program (var "a"))`},
+		{"or truthy", `| "x" a`, optimize.Simplify, `(program "x")`},
+		{"chain drops literals", `; "" ; 1 a`, optimize.Simplify, `(program (var "a"))`},
+		{"chain drops blocks", `; BLOCK O 1 a`, optimize.Simplify, `(program (var "a"))`},
+		{"chain keeps variables", `; a b`, optimize.Simplify, `(program (chain (var "a") (var "b")))`},
+		{"chain keeps last literal", `; O 1 2`, optimize.Simplify, "(program (chain (O 1) 2))"},
+		{"chain flattens", `; (; O 1 O 2) (; O 3 O 4)`, optimize.Simplify, "(program (chain (O 1) (chain (O 2) (chain (O 3) (O 4)))))"},
+		{"chain flattens pruned branches", `; IF T (; O 1 O 2) 0 O 3`, optimize.Simplify, "(program (chain (O 1) (chain (O 2) (O 3))))"},
+		{"block body", "BLOCK : IF T a b", optimize.Simplify, `(program (BLOCK (var "a")))`},
+		{"fold arithmetic", "+ 1 * 2 - 10 / 9 3", optimize.Fold, "(program 15)"},
+		{"fold strings", `+ "foo" * "ab" 2`, optimize.Fold, `(program "fooabab")`},
+		{"fold comparisons", `& < 1 2 ? "a" "a"`, optimize.Fold, "(program true)"},
+		{"fold not", "! 0", optimize.Fold, "(program true)"},
+		{"fold exponent", "^ 2 10", optimize.Fold, "(program 1024)"},
+		{"fold builtins", `+ LENGTH "abc" ASCII "A"`, optimize.Fold, "(program 68)"},
+		{"fold get", `GET "hello" 1 3`, optimize.Fold, `(program "ell")`},
+		{"fold substitute", `SUBSTITUTE "hello" 1 3 "ipp"`, optimize.Fold, `(program "hippo")`},
+		{"fold conditions", "IF < 1 2 a b", optimize.Fold, `(program (var "a"))`},
+		{"don't fold errors", "/ 1 0", optimize.Fold, "(program (div 1 0))"},
+		{"don't fold variables", "+ 1 a", optimize.Fold, `(program (add 1 (var "a")))`},
+		{"don't fold overflow", "* 65536 65536", optimize.Fold, "(program (mul 65536 65536))"},
+		{"don't fold ints beyond 32 bits", "< 3000000000 1", optimize.Fold, "(program (less 3000000000 1))"},
+		{"don't fold strings beyond 32 bits", `< 1 "3000000000"`, optimize.Fold, `(program (less 1 "3000000000"))`},
+		{"if beyond 32 bits", "IF 4294967296 a b", optimize.Simplify, `(program (IF 4294967296 (var "a") (var "b")))`},
+		{"don't fold huge strings", `* "a" 100000`, optimize.Fold, `(program (mul "a" 100000))`},
+		{"don't fold impure builtins", "+ RANDOM 1", optimize.Fold, "(program (add RANDOM 1))"},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := lexer.New()
+			p := parser.New(l)
+			g := value.NewGlobalStore()
+			program, err := p.Parse(g, strings.NewReader(tc.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			original := program.String()
+
+			if got := optimize.Program(program, tc.level).String(); got != tc.want {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+
+			if got := program.String(); got != original {
+				t.Errorf("want original program %v to be unchanged, got %v", original, got)
+			}
+		})
+	}
+}
+
+func TestProgramNoop(t *testing.T) {
+	t.Parallel()
+
+	// The lexer treats ':' as whitespace, so NOOP nodes are built directly
+	g := value.NewGlobalStore()
+	x := value.NewVariable(g.New("x"), ast.Span{})
+	noop := ast.NewUnary(ast.Span{}, token.Noop, ast.NewUnary(ast.Span{}, token.Noop, x))
+	program := ast.Program{Root: ast.NewUnary(ast.Span{}, token.Not, noop)}
+
+	want := `(program (not (var "x")))`
+	if got := optimize.Program(program, optimize.Simplify).String(); got != want {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestProgramResults(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		source string
+	}{
+		{"arithmetic", `; = x 5 : O + * x 2 - 10 / 9 3`},
+		{"loop", `; = i 0 ; WHILE < i 5 ; O i = i + i 1 : WHILE F O "unreachable"`},
+		{"branches", `; = x 1 ; O IF T "yes" "no" ; O IF x "yes" "no" : O & 0 O "unreachable"`},
+		{"blocks", `; = f BLOCK ; "unused" : + 1 2 : O CALL f`},
+		{"chains", `; (; O 1 : O 2) ; "" ; 3 : O 4`},
+		{"strings", `; = s + "foo" * "ab" 2 : O + LENGTH s GET s 1 2`},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var want string
+			for level := optimize.None; level <= optimize.Fold; level++ {
+				l := lexer.New()
+				p := parser.New(l)
+				g := value.NewGlobalStore()
+				program, err := p.Parse(g, strings.NewReader(tc.source))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var stdout bytes.Buffer
+				i := interpreter.New(g, p, interpreter.WithStdout(&stdout), interpreter.WithStderr(io.Discard))
+				if _, err := i.Execute(optimize.Program(program, level)); err != nil {
+					t.Fatalf("level %v: %v", level, err)
+				}
+
+				if level == optimize.None {
+					want = stdout.String()
+				} else if got := stdout.String(); got != want {
+					t.Errorf("level %v: want output %q, got %q", level, want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package parser
+
+import "github.com/polyscone/knight/value"
+
+// Option configures a Parser when it is created with New.
+type Option func(p *Parser)
+
+// WithHeap sets the heap that integer and string literals are interned in.
+// The default is value.DefaultHeap.
+func WithHeap(h *value.Heap) Option {
+	return func(p *Parser) {
+		p.heap = h
+	}
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
@@ -27,6 +28,28 @@ var builtinArities = map[byte]int{
 	'W': 2,
 }
 
+// Error describes a problem that was found while parsing along with the region
+// of source code that caused it.
+type Error struct {
+	Span ast.Span
+	Err  error
+}
+
+// Error returns the error message prefixed with its location.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %v", e.Span, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Location returns the region of source code that caused the error.
+func (e *Error) Location() ast.Span {
+	return e.Span
+}
+
 // Lexer should provide a stream of tokens from some source code.
 type Lexer interface {
 	Load(r io.ByteScanner)
@@ -37,15 +60,27 @@ type Lexer interface {
 // Parser holds the state for a parser than can transform a stream of Knight
 // tokens into an AST.
 type Parser struct {
-	lexer   Lexer
-	globals *value.GlobalStore
+	lexer    Lexer
+	globals  *value.GlobalStore
+	filename string
+	last     token.Token
+	heap     *value.Heap
 }
 
 // Parse will load the source code int the given byte scanner into its lexer and
 // build an AST from the resulting token stream.
 func (p *Parser) Parse(globals *value.GlobalStore, r io.ByteScanner) (ast.Program, error) {
+	return p.ParseFile(globals, "", r)
+}
+
+// ParseFile works the same way as Parse, but every node in the resulting AST,
+// and any error that is returned, will record the given filename as part of
+// its source span.
+func (p *Parser) ParseFile(globals *value.GlobalStore, filename string, r io.ByteScanner) (ast.Program, error) {
 	p.lexer.Load(r)
 	p.globals = globals
+	p.filename = filename
+	p.last = token.Token{}
 
 	program := ast.Program{}
 
@@ -61,31 +96,39 @@ func (p *Parser) Parse(globals *value.GlobalStore, r io.ByteScanner) (ast.Progra
 
 func (p *Parser) parseExpr() (ast.Node, error) {
 	tok, err := p.lexer.Consume()
+	p.last = tok
 	if err != nil {
-		return ast.Invalid, err
+		return ast.Invalid, p.newError(tok, err)
 	}
 
 	switch tok.Kind {
 	case token.Integer:
 		i, err := strconv.Atoi(tok.Lexeme)
+		if errors.Is(err, strconv.ErrRange) {
+			// Literals that don't fit in an int are kept exactly, and the
+			// interpreter's integer semantics decide what happens to them
+			return p.heap.NewBigInt(value.AtoiBig(tok.Lexeme)), nil
+		}
 		if err != nil {
-			return ast.Invalid, err
+			return ast.Invalid, p.newError(tok, err)
 		}
 
-		return value.NewInt(i), nil
+		return p.heap.NewInt(i), nil
 	case token.String:
-		return value.NewString(tok.Lexeme), nil
+		return p.heap.NewString(tok.Lexeme), nil
 	case token.True, token.False:
 		return value.NewBool(tok.Kind == token.True), nil
 	case token.Null:
 		return value.NewNull(), nil
-	case token.Not, token.Noop, token.System:
+	case token.List:
+		return value.NewList(nil), nil
+	case token.Not, token.Noop, token.System, token.Box, token.Head, token.Tail:
 		value, err := p.parseExpr()
 		if err != nil {
 			return ast.Invalid, err
 		}
 
-		return ast.NewUnary(tok.Kind, value), nil
+		return ast.NewUnary(p.span(tok), tok.Kind, value), nil
 	case token.And,
 		token.Or,
 		token.Add,
@@ -110,17 +153,17 @@ func (p *Parser) parseExpr() (ast.Node, error) {
 			return ast.Invalid, err
 		}
 
-		return ast.NewBinary(tok.Kind, lhs, rhs), nil
+		return ast.NewBinary(p.span(tok), tok.Kind, lhs, rhs), nil
 	case token.Variable:
-		return p.globals.New(tok.Lexeme), nil
+		return value.NewVariable(p.globals.New(tok.Lexeme), p.span(tok)), nil
 	case token.Call:
 		letter := tok.Lexeme[0]
 		arity, ok := builtinArities[letter]
 		if !ok {
-			return ast.Invalid, fmt.Errorf("unexpected function %q", tok.Lexeme)
+			return ast.Invalid, p.newError(tok, fmt.Errorf("unexpected function %q", tok.Lexeme))
 		}
 
-		args := make([]ast.Node, arity)
+		var args [4]ast.Node
 		for i := 0; i < arity; i++ {
 			arg, err := p.parseExpr()
 			if err != nil {
@@ -130,13 +173,84 @@ func (p *Parser) parseExpr() (ast.Node, error) {
 			args[i] = arg
 		}
 
-		return ast.NewCall(tok.Lexeme, args), nil
+		return newBuiltin(p.span(tok), tok.Lexeme, args), nil
+	case token.EOF:
+		return ast.Invalid, p.newError(tok, errors.New("unexpected end of input"))
 	default:
-		return ast.Invalid, fmt.Errorf("unexpected token: %s", tok)
+		return ast.Invalid, p.newError(tok, fmt.Errorf("unexpected token %#q (%s)", tok.Lexeme, tok.Kind))
 	}
 }
 
+// span returns a source span that starts at the given token and ends after the
+// most recently consumed token.
+func (p *Parser) span(start token.Token) ast.Span {
+	return ast.Span{
+		File:    p.filename,
+		Line:    start.Line,
+		Col:     start.Col,
+		EndLine: p.last.EndLine,
+		EndCol:  p.last.EndCol,
+	}
+}
+
+func (p *Parser) newError(tok token.Token, err error) error {
+	span := ast.Span{
+		File:    p.filename,
+		Line:    tok.Line,
+		Col:     tok.Col,
+		EndLine: tok.EndLine,
+		EndCol:  tok.EndCol,
+	}
+
+	return &Error{Span: span, Err: err}
+}
+
 // New returns a new initialised Parser.
-func New(lexer Lexer) *Parser {
-	return &Parser{lexer: lexer}
+func New(lexer Lexer, opts ...Option) *Parser {
+	p := &Parser{lexer: lexer, heap: value.DefaultHeap()}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// newBuiltin returns the node for a call to the builtin function with the
+// given name, using as many of the given args as the function takes.
+func newBuiltin(span ast.Span, name string, args [4]ast.Node) ast.Node {
+	switch name[0] {
+	case 'A':
+		return ast.NewASCII(span, name, args[0])
+	case 'B':
+		return ast.NewBlock(span, name, args[0])
+	case 'C':
+		return ast.NewCallBlock(span, name, args[0])
+	case 'D':
+		return ast.NewDump(span, name, args[0])
+	case 'E':
+		return ast.NewEval(span, name, args[0])
+	case 'G':
+		return ast.NewGet(span, name, args[0], args[1], args[2])
+	case 'I':
+		return ast.NewIf(span, name, args[0], args[1], args[2])
+	case 'L':
+		return ast.NewLength(span, name, args[0])
+	case 'O':
+		return ast.NewOutput(span, name, args[0])
+	case 'P':
+		return ast.NewPrompt(span, name)
+	case 'Q':
+		return ast.NewQuit(span, name, args[0])
+	case 'R':
+		return ast.NewRandom(span, name)
+	case 'S':
+		return ast.NewSubstitute(span, name, args[0], args[1], args[2], args[3])
+	case 'W':
+		return ast.NewWhile(span, name, args[0], args[1])
+	}
+
+	arity := builtinArities[name[0]]
+
+	return ast.NewCall(span, name, args[:arity])
 }
@@ -1,9 +1,12 @@
 package parser_test
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 
+	"github.com/polyscone/knight/ast"
 	"github.com/polyscone/knight/lexer"
 	"github.com/polyscone/knight/parser"
 	"github.com/polyscone/knight/value"
@@ -92,11 +95,36 @@ func TestGeneratedASTStructure(t *testing.T) {
 			"; 0 1",
 			"(program (chain 0 1))",
 		},
+		{
+			"empty list (@)",
+			"@",
+			"(program (list))",
+		},
+		{
+			"box (,)",
+			", 1",
+			"(program (box 1))",
+		},
+		{
+			"head ([)",
+			"[ @",
+			"(program (head (list)))",
+		},
+		{
+			"tail (])",
+			"] \"abc\"",
+			`(program (tail "abc"))`,
+		},
 		// {
 		// 	"no-op (:)",
 		// 	": 1",
 		// 	"(program (noop 1))",
 		// },
+		{
+			"big integer literal",
+			"+ 1 123456789012345678901234567890",
+			"(program (add 1 123456789012345678901234567890))",
+		},
 		{
 			"word function ascii",
 			"A 1",
@@ -318,3 +346,159 @@ func TestGeneratedASTStructure(t *testing.T) {
 		})
 	}
 }
+
+func TestSpans(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		source string
+		want   ast.Span
+	}{
+		{"binary", "+ 1 2", ast.Span{File: "test.kn", Line: 1, Col: 1, EndLine: 1, EndCol: 6}},
+		{"binary leading whitespace", "  + 1 2", ast.Span{File: "test.kn", Line: 1, Col: 3, EndLine: 1, EndCol: 8}},
+		{"unary", "! foo", ast.Span{File: "test.kn", Line: 1, Col: 1, EndLine: 1, EndCol: 6}},
+		{"call", "OUTPUT 'foo'", ast.Span{File: "test.kn", Line: 1, Col: 1, EndLine: 1, EndCol: 13}},
+		{"multiline", "\n; = x 1\n  : x", ast.Span{File: "test.kn", Line: 2, Col: 1, EndLine: 3, EndCol: 6}},
+		{"variable", "\n\n  foo", ast.Span{File: "test.kn", Line: 3, Col: 3, EndLine: 3, EndCol: 6}},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := lexer.New()
+			p := parser.New(l)
+			g := value.NewGlobalStore()
+			program, err := p.ParseFile(g, "test.kn", strings.NewReader(tc.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, ok := ast.Locate(program.Root)
+			if !ok {
+				t.Fatalf("want root node %v to have a span", program.Root)
+			}
+
+			if got != tc.want {
+				t.Errorf("want %#v, got %#v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"missing argument", "+ 1", "test.kn:1:4: unexpected end of input"},
+		{"unknown token", "\n+ 1 .", "test.kn:2:5: unknown token"},
+		{"unexpected function", "; 1 X", `test.kn:1:5: unexpected function "X"`},
+		{"unterminated string", "; 1\nOUTPUT 'foo", "test.kn:2:8: unterminated string"},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := lexer.New()
+			p := parser.New(l)
+			g := value.NewGlobalStore()
+			_, err := p.ParseFile(g, "test.kn", strings.NewReader(tc.source))
+
+			var perr *parser.Error
+			if !errors.As(err, &perr) {
+				t.Fatalf("want *parser.Error, got %#v", err)
+			}
+
+			if got := err.Error(); got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestBuiltinNodes(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		source string
+		want   string
+		args   []ast.Node
+	}{
+		{"ascii", "ASCII 1", "*ast.ASCII", []ast.Node{value.NewInt(1)}},
+		{"block", "B 1", "*ast.Block", []ast.Node{value.NewInt(1)}},
+		{"call", "CALL 1", "*ast.CallBlock", []ast.Node{value.NewInt(1)}},
+		{"dump", "DUMP 1", "*ast.Dump", []ast.Node{value.NewInt(1)}},
+		{"eval", "EVAL 1", "*ast.Eval", []ast.Node{value.NewInt(1)}},
+		{"get", "GET 1 2 3", "*ast.Get", []ast.Node{value.NewInt(1), value.NewInt(2), value.NewInt(3)}},
+		{"if", "IF 1 2 3", "*ast.If", []ast.Node{value.NewInt(1), value.NewInt(2), value.NewInt(3)}},
+		{"length", "LENGTH 1", "*ast.Length", []ast.Node{value.NewInt(1)}},
+		{"output", "O 1", "*ast.Output", []ast.Node{value.NewInt(1)}},
+		{"prompt", "PROMPT", "*ast.Prompt", []ast.Node{}},
+		{"quit", "QUIT 1", "*ast.Quit", []ast.Node{value.NewInt(1)}},
+		{"random", "R", "*ast.Random", []ast.Node{}},
+		{"substitute", "SET 1 2 3 4", "*ast.Substitute", []ast.Node{value.NewInt(1), value.NewInt(2), value.NewInt(3), value.NewInt(4)}},
+		{"while", "WHILE 1 2", "*ast.While", []ast.Node{value.NewInt(1), value.NewInt(2)}},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := lexer.New()
+			p := parser.New(l)
+			g := value.NewGlobalStore()
+			program, err := p.Parse(g, strings.NewReader(tc.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := fmt.Sprintf("%T", program.Root); got != tc.want {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+
+			// Specialised nodes should print exactly like a generic call
+			name := strings.Fields(tc.source)[0]
+			call := ast.NewCall(ast.Span{}, name, tc.args)
+			for _, style := range []ast.Style{ast.StyleSexpr, ast.StyleTree, ast.StyleWaterfall} {
+				if want, got := call.ASTString(style), program.Root.ASTString(style); want != got {
+					t.Errorf("want %q, got %q", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWithHeap(t *testing.T) {
+	t.Parallel()
+
+	h := value.NewHeap()
+	p := parser.New(lexer.New(), parser.WithHeap(h))
+	g := value.NewGlobalStore()
+	program, err := p.Parse(g, strings.NewReader(`+ "foo" 300`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binary, ok := program.Root.(*ast.Binary)
+	if !ok {
+		t.Fatalf("want *ast.Binary, got %T", program.Root)
+	}
+
+	if want, got := h.NewString("foo"), binary.LHS; want != got {
+		t.Errorf("want %p, got %p", want, got)
+	}
+
+	if got := value.NewString("foo"); got == binary.LHS {
+		t.Errorf("want a string from the parser's heap, got the default heap's %p", got)
+	}
+}
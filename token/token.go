@@ -18,6 +18,7 @@ const (
 	True
 	False
 	Null
+	List
 
 	And
 	Or
@@ -36,6 +37,9 @@ const (
 	System
 	Chain
 	Noop
+	Box
+	Head
+	Tail
 )
 
 // Kind is used to describe the kind of token.
@@ -64,6 +68,8 @@ func (k Kind) String() string {
 		return "false"
 	case Null:
 		return "null"
+	case List:
+		return "list"
 	case And:
 		return "and"
 	case Or:
@@ -98,17 +104,27 @@ func (k Kind) String() string {
 		return "chain"
 	case Noop:
 		return "noop"
+	case Box:
+		return "box"
+	case Head:
+		return "head"
+	case Tail:
+		return "tail"
 	}
 
 	return strconv.Itoa(int(k))
 }
 
 // Token represents an atomic piece of code.
+// Line and Col give the position of the token's first byte, and EndLine and
+// EndCol give the position immediately after its last byte.
 type Token struct {
-	Kind   Kind
-	Lexeme string
-	Line   int
-	Col    int
+	Kind    Kind
+	Lexeme  string
+	Line    int
+	Col     int
+	EndLine int
+	EndCol  int
 }
 
 // String returns a string representation of the Token along with line and
@@ -0,0 +1,134 @@
+package transpile
+
+// prelude is written after the generated code, and holds the runtime support
+// that every transpiled program needs.
+//
+// Everything a program does goes through the exported methods on the
+// interpreter that it creates in main, which are the same ones that the
+// interpreter's backends use, so that transpiled programs share the
+// interpreter's semantics, call stack, and error reporting.
+const prelude = `
+// i runs the program, and is used for every builtin function, for EVAL, and
+// to keep track of the Knight call stack.
+var i *interpreter.Interpreter
+
+func main() {
+	i = interpreter.New(globals, parser.New(lexer.New()))
+
+	if _, err := i.Execute(ast.Program{Root: &native{fn: run, src: "<program>"}}); err != nil {
+		var exit *interpreter.ExitError
+		if errors.As(err, &exit) {
+			os.Exit(exit.Code)
+		}
+
+		fmt.Println(err)
+
+		var rerr *interpreter.RuntimeError
+		if errors.As(err, &rerr) {
+			fmt.Print("\nknight stack:\n", rerr.StackTrace())
+		}
+
+		os.Exit(1)
+	}
+}
+
+// native is a block body that has been compiled to a Go function.
+// It implements interpreter.Native so that blocks can still be called by code
+// that's run with EVAL.
+type native struct {
+	fn  func() (value.Value, error)
+	src string
+}
+
+// String returns the s-expression of the body that the block was compiled
+// from, so that errors that print blocks are the same as the interpreter's.
+func (n *native) String() string {
+	return n.src
+}
+
+// ASTString returns the same s-expression as String in every style, because
+// only the s-expression is kept.
+func (n *native) ASTString(style ast.Style) string {
+	return n.src
+}
+
+// Eval calls the compiled function.
+func (n *native) Eval(*interpreter.Interpreter) (value.Value, error) {
+	return n.fn()
+}
+
+// at returns a span in the program's source file.
+func at(line, col, endLine, endCol int) ast.Span {
+	return ast.Span{File: file, Line: line, Col: col, EndLine: endLine, EndCol: endCol}
+}
+
+// get returns the value of the given global, calling it if it holds a block.
+func get(g *value.Global, site ast.Span) (value.Value, error) {
+	if g.Value == nil {
+		return nil, fmt.Errorf("attempted to access undefined variable %q", g.Name)
+	}
+
+	if _, ok := g.Value.(*value.Block); !ok {
+		return g.Value, nil
+	}
+
+	return call(g.Value, g.Name, site)
+}
+
+// call calls the given value if it's a block, using the given name for its
+// frame on the Knight call stack, and returns any other value as it is.
+func call(v value.Value, name string, site ast.Span) (value.Value, error) {
+	b, ok := v.(*value.Block)
+	if !ok {
+		return v, nil
+	}
+
+	if err := i.Enter(name, site); err != nil {
+		return nil, err
+	}
+	defer i.Leave()
+
+	if n, ok := b.Value.(*native); ok {
+		return n.fn()
+	}
+
+	// Blocks that were created by EVAL are walked by the interpreter
+	return i.Call(b)
+}
+
+// eval runs the string conversion of the given value as a program.
+func eval(v value.Value, site ast.Span) (value.Value, error) {
+	if err := i.Enter("<eval>", site); err != nil {
+		return nil, err
+	}
+	defer i.Leave()
+
+	return i.Eval(v)
+}
+
+// prompt reads a line from stdin, making sure that any prompt text has been
+// written first.
+func prompt() (value.Value, error) {
+	if err := i.Flush(); err != nil {
+		return nil, err
+	}
+
+	return i.Prompt(i.Stdin())
+}
+
+// test returns the boolean conversion of the given value.
+func test(v value.Value) (bool, error) {
+	b, err := value.ToBool(v)
+	if err != nil {
+		return false, err
+	}
+
+	return b.Value, nil
+}
+
+// fail returns an error with the given message, for expressions that can
+// only ever fail.
+func fail(msg string) (value.Value, error) {
+	return nil, errors.New(msg)
+}
+`
@@ -0,0 +1,545 @@
+// Package transpile translates Knight programs into Go source code, so that
+// they can be compiled ahead of time into standalone programs.
+package transpile
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+
+	"github.com/polyscone/knight/ast"
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/token"
+	"github.com/polyscone/knight/value"
+)
+
+// Program returns the source code of a Go main package that runs the given
+// program.
+//
+// Every block in the program is compiled to a Go function, and the builtin
+// functions are implemented by calling the same methods on an
+// interpreter.Interpreter that the interpreter itself uses, so a transpiled
+// program produces the same output, errors, and stack traces as the
+// interpreter does.
+// Code that's run with EVAL can't be known ahead of time, so EVAL parses and
+// runs its code with the interpreter, and blocks that are created by either
+// side can be called by the other.
+//
+// Unlike the interpreter, transpiled programs don't evaluate calls in tail
+// position without nesting them, so deep tail recursion stops with an error
+// once it reaches the interpreter's maximum call depth, and stack traces
+// include the callers that the interpreter would have replaced.
+//
+// The generated code imports this module's packages, so it has to be built
+// inside a module that requires this one.
+func Program(program ast.Program) ([]byte, error) {
+	if program.Root == nil {
+		return nil, fmt.Errorf("cannot transpile an empty program")
+	}
+
+	t := transpiler{
+		globals:  make(map[*value.Global]string),
+		literals: make(map[string]string),
+		interp:   interpreter.New(nil, nil),
+	}
+
+	t.begin("run")
+	result, err := t.expr(program.Root)
+	if err != nil {
+		return nil, err
+	}
+	t.end(result)
+
+	var file string
+	if span, ok := ast.Locate(program.Root); ok {
+		file = span.File
+	}
+
+	var out bytes.Buffer
+
+	out.WriteString("// Code generated by knight build. DO NOT EDIT.\n\n")
+	out.WriteString("package main\n\n")
+	out.WriteString("import (\n")
+	out.WriteString("\t\"errors\"\n\t\"fmt\"\n\t\"os\"\n\n")
+	out.WriteString("\t\"github.com/polyscone/knight/ast\"\n")
+	out.WriteString("\t\"github.com/polyscone/knight/interpreter\"\n")
+	out.WriteString("\t\"github.com/polyscone/knight/lexer\"\n")
+	out.WriteString("\t\"github.com/polyscone/knight/parser\"\n")
+	out.WriteString("\t\"github.com/polyscone/knight/value\"\n")
+	out.WriteString(")\n\n")
+	fmt.Fprintf(&out, "// file is the name of the source file that the program was transpiled from.\nconst file = %q\n\n", file)
+	out.WriteString("// globals holds every global that the program uses, along with any that are\n// declared by EVAL.\n")
+	out.WriteString("var globals = value.NewGlobalStore()\n\n")
+
+	writeVars(&out, t.decls)
+
+	for _, fn := range t.done {
+		out.Write(fn)
+	}
+
+	out.WriteString(prelude)
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+
+	return src, nil
+}
+
+// writeVars writes the given declarations as a single var block.
+func writeVars(out *bytes.Buffer, decls []string) {
+	if len(decls) == 0 {
+		return
+	}
+
+	out.WriteString("var (\n")
+	for _, decl := range decls {
+		out.WriteString(decl)
+		out.WriteString("\n")
+	}
+	out.WriteString(")\n\n")
+}
+
+// transpiler holds the state for translating a single program.
+type transpiler struct {
+	// globals and literals map each global, and the Go expression that
+	// creates each literal, to the name of the package level variable that
+	// holds it.
+	globals  map[*value.Global]string
+	literals map[string]string
+	decls    []string
+
+	// blocks counts the number of blocks that have been compiled.
+	blocks int
+
+	// interp has the same integer semantics as the interpreter that runs the
+	// transpiled program, so that literals can be normalised in advance.
+	interp *interpreter.Interpreter
+
+	// fns holds the functions that are being generated, innermost last, and
+	// done holds the ones that are finished.
+	fns  []*function
+	done [][]byte
+}
+
+// function is a Go function that's being generated.
+type function struct {
+	name  string
+	body  bytes.Buffer
+	temps int
+
+	// usesErr records whether the body needs an err variable.
+	usesErr bool
+}
+
+func (t *transpiler) fn() *function {
+	return t.fns[len(t.fns)-1]
+}
+
+// begin starts generating a new function with the given name.
+func (t *transpiler) begin(name string) {
+	t.fns = append(t.fns, &function{name: name})
+}
+
+// end finishes the current function, which returns the given expression.
+func (t *transpiler) end(result string) {
+	fn := t.fn()
+	t.fns = t.fns[:len(t.fns)-1]
+
+	var out bytes.Buffer
+
+	fmt.Fprintf(&out, "func %v() (value.Value, error) {\n", fn.name)
+	if fn.usesErr {
+		out.WriteString("var err error\n\n")
+	}
+	out.Write(fn.body.Bytes())
+	fmt.Fprintf(&out, "return %v, nil\n}\n\n", result)
+
+	t.done = append(t.done, out.Bytes())
+}
+
+func (t *transpiler) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&t.fn().body, format, args...)
+}
+
+// temp declares a new variable of the given type in the current function and
+// returns its name.
+func (t *transpiler) temp(prefix, typ string) string {
+	fn := t.fn()
+	name := prefix + strconv.Itoa(fn.temps)
+	fn.temps++
+
+	t.printf("var %v %v\n", name, typ)
+
+	return name
+}
+
+// try assigns the result of a call that can fail to dst, which is either a
+// declared variable or "_", and locates any error at the given span.
+func (t *transpiler) try(dst, call string, span ast.Span) {
+	t.fn().usesErr = true
+	t.printf("if %v, err = %v; err != nil {\nreturn nil, i.Locate(%v, err)\n}\n", dst, call, at(span))
+}
+
+// test assigns the boolean conversion of the given expression to a new
+// variable and returns its name.
+func (t *transpiler) test(expr string, span ast.Span) string {
+	c := t.temp("c", "bool")
+	t.try(c, "test("+expr+")", span)
+
+	return c
+}
+
+// global returns the name of the variable that holds the given global.
+func (t *transpiler) global(g *value.Global) string {
+	name, ok := t.globals[g]
+	if !ok {
+		name = "g" + strconv.Itoa(len(t.globals))
+		t.globals[g] = name
+		t.decls = append(t.decls, fmt.Sprintf("%v = globals.New(%q)", name, g.Name))
+	}
+
+	return name
+}
+
+// literal returns the name of the variable that holds the given literal.
+func (t *transpiler) literal(v value.Value) (string, error) {
+	var init string
+	switch v := v.(type) {
+	case *value.Bool:
+		init = fmt.Sprintf("value.NewBool(%v)", v.Value)
+	case *value.Int:
+		lit, err := t.interp.Literal(v)
+		if err != nil {
+			return "", err
+		}
+
+		//nolint:forcetypeassert // integers are always normalised to integers
+		n := lit.(*value.Int)
+		init = fmt.Sprintf("value.NewInt(%v)", n.Value)
+		if n.IsBig() {
+			init = fmt.Sprintf("value.NewBigInt(value.AtoiBig(%q))", n.Big().String())
+		}
+	case *value.String:
+		init = fmt.Sprintf("value.NewString(%q)", v.Value())
+	case *value.Null:
+		init = "value.NewNull()"
+	case *value.List:
+		// The only list literal is the empty list
+		if v.Len() != 0 {
+			return "", fmt.Errorf("cannot transpile literal: %v", v)
+		}
+
+		init = "value.NewList(nil)"
+	default:
+		return "", fmt.Errorf("cannot transpile literal: %v", v)
+	}
+
+	name, ok := t.literals[init]
+	if !ok {
+		name = "k" + strconv.Itoa(len(t.literals))
+		t.literals[init] = name
+		t.decls = append(t.decls, fmt.Sprintf("%v = %v", name, init))
+	}
+
+	return name, nil
+}
+
+// block compiles the given block body to a function and returns the name of
+// the variable that holds its native node.
+func (t *transpiler) block(body ast.Node) (string, error) {
+	n := t.blocks
+	t.blocks++
+
+	t.begin("block" + strconv.Itoa(n))
+	result, err := t.expr(body)
+	if err != nil {
+		return "", err
+	}
+	t.end(result)
+
+	name := "n" + strconv.Itoa(n)
+	t.decls = append(t.decls, fmt.Sprintf("%v = &native{fn: block%v, src: %q}", name, n, body.String()))
+
+	return name, nil
+}
+
+// expr generates the code that evaluates the given node and returns a Go
+// expression that holds its result.
+func (t *transpiler) expr(node ast.Node) (string, error) {
+	switch v := node.(type) {
+	case *value.Bool, *value.Int, *value.String, *value.Null, *value.List:
+		//nolint:forcetypeassert // every case is a value
+		return t.literal(v.(value.Value))
+	}
+
+	dst := t.temp("v", "value.Value")
+	if err := t.eval(node, dst); err != nil {
+		return "", err
+	}
+
+	return dst, nil
+}
+
+// exprs generates the code that evaluates each of the given nodes in order.
+func (t *transpiler) exprs(nodes ...ast.Node) ([]string, error) {
+	results := make([]string, len(nodes))
+	for n, node := range nodes {
+		result, err := t.expr(node)
+		if err != nil {
+			return nil, err
+		}
+
+		results[n] = result
+	}
+
+	return results, nil
+}
+
+// eval generates the code that evaluates the given node and assigns its
+// result to dst, which is either a declared variable or "_" if the result
+// isn't used.
+func (t *transpiler) eval(node ast.Node, dst string) error {
+	span, _ := ast.Locate(node)
+
+	// builtin generates a call to an interpreter method with the results of
+	// the given arguments
+	builtin := func(method string, args ...ast.Node) error {
+		results, err := t.exprs(args...)
+		if err != nil {
+			return err
+		}
+
+		if method == "Dump" || method == "Output" {
+			results = append([]string{"i.Stdout()"}, results...)
+		}
+
+		t.try(dst, fmt.Sprintf("i.%v(%v)", method, strings.Join(results, ", ")), span)
+
+		return nil
+	}
+
+	switch v := node.(type) {
+	case *value.Bool, *value.Int, *value.String, *value.Null, *value.List:
+		if dst == "_" {
+			return nil
+		}
+
+		//nolint:forcetypeassert // every case is a value
+		name, err := t.literal(v.(value.Value))
+		if err != nil {
+			return err
+		}
+
+		t.printf("%v = %v\n", dst, name)
+	case *value.Variable:
+		t.try(dst, fmt.Sprintf("get(%v, %v)", t.global(v.Global), at(v.Span)), v.Span)
+	case *ast.Block:
+		// Blocks with a literal body wrap the literal itself, like the
+		// interpreter's blocks do, so that they're dumped the same way
+		var body string
+		var err error
+		switch v.Body.(type) {
+		case *value.Bool, *value.Int, *value.String, *value.Null, *value.List:
+			body, err = t.expr(v.Body)
+		default:
+			body, err = t.block(v.Body)
+		}
+		if err != nil {
+			return err
+		}
+
+		if dst != "_" {
+			t.printf("%v = value.NewBlock(%v)\n", dst, body)
+		}
+	case *ast.CallBlock:
+		arg, err := t.expr(v.Block)
+		if err != nil {
+			return err
+		}
+
+		t.try(dst, fmt.Sprintf("call(%v, %q, %v)", arg, "<block>", at(v.Span)), v.Span)
+	case *ast.Eval:
+		arg, err := t.expr(v.Arg)
+		if err != nil {
+			return err
+		}
+
+		t.try(dst, fmt.Sprintf("eval(%v, %v)", arg, at(v.Span)), v.Span)
+	case *ast.If:
+		condition, err := t.expr(v.Condition)
+		if err != nil {
+			return err
+		}
+
+		b := t.test(condition, v.Span)
+
+		t.printf("if %v {\n", b)
+		if err := t.eval(v.Consequence, dst); err != nil {
+			return err
+		}
+		t.printf("} else {\n")
+		if err := t.eval(v.Alternative, dst); err != nil {
+			return err
+		}
+		t.printf("}\n")
+	case *ast.While:
+		t.fn().usesErr = true
+		t.printf("for {\n")
+		t.printf("if err = i.Cancelled(); err != nil {\nreturn nil, i.Locate(%v, err)\n}\n\n", at(v.Span))
+
+		condition, err := t.expr(v.Condition)
+		if err != nil {
+			return err
+		}
+
+		b := t.test(condition, v.Span)
+		t.printf("if !%v {\nbreak\n}\n\n", b)
+
+		if err := t.eval(v.Body, "_"); err != nil {
+			return err
+		}
+		t.printf("}\n")
+
+		if dst != "_" {
+			t.printf("%v = value.NewNull()\n", dst)
+		}
+	case *ast.ASCII:
+		return builtin("ASCII", v.Arg)
+	case *ast.Dump:
+		return builtin("Dump", v.Arg)
+	case *ast.Get:
+		return builtin("Get", v.Str, v.Start, v.Count)
+	case *ast.Length:
+		return builtin("Length", v.Arg)
+	case *ast.Output:
+		return builtin("Output", v.Arg)
+	case *ast.Prompt:
+		t.try(dst, "prompt()", v.Span)
+	case *ast.Quit:
+		return builtin("Quit", v.Arg)
+	case *ast.Random:
+		return builtin("Random")
+	case *ast.Substitute:
+		return builtin("Substitute", v.Str, v.Start, v.Count, v.Replacement)
+	case *ast.Call:
+		t.try(dst, fmt.Sprintf("fail(%q)", "undefined function: "+v.Name), v.Span)
+	case *ast.Unary:
+		switch v.Op {
+		case token.Noop:
+			return t.eval(v.Node, dst)
+		case token.Not:
+			return builtin("Not", v.Node)
+		case token.System:
+			return builtin("System", v.Node)
+		case token.Box:
+			return builtin("Box", v.Node)
+		case token.Head:
+			return builtin("Head", v.Node)
+		case token.Tail:
+			return builtin("Tail", v.Node)
+		default:
+			return fmt.Errorf("cannot transpile unary operator: %s", v)
+		}
+	case *ast.Binary:
+		return t.binary(v, dst)
+	default:
+		return fmt.Errorf("cannot transpile node: %s", node)
+	}
+
+	return nil
+}
+
+// binary generates the code for a binary operation, like eval does.
+func (t *transpiler) binary(v *ast.Binary, dst string) error {
+	var method string
+	switch v.Op {
+	case token.Chain:
+		if err := t.eval(v.LHS, "_"); err != nil {
+			return err
+		}
+
+		return t.eval(v.RHS, dst)
+	case token.And, token.Or:
+		lhs, err := t.expr(v.LHS)
+		if err != nil {
+			return err
+		}
+
+		b := t.test(lhs, v.Span)
+
+		// The RHS is only evaluated if the LHS doesn't short-circuit
+		if v.Op == token.And {
+			t.printf("if %v {\n", b)
+		} else {
+			t.printf("if !%v {\n", b)
+		}
+		if err := t.eval(v.RHS, dst); err != nil {
+			return err
+		}
+		if dst != "_" {
+			t.printf("} else {\n%v = %v\n", dst, lhs)
+		}
+		t.printf("}\n")
+
+		return nil
+	case token.Assign:
+		variable, ok := v.LHS.(*value.Variable)
+		if !ok {
+			// The RHS is still evaluated first, like it is by the interpreter
+			if err := t.eval(v.RHS, "_"); err != nil {
+				return err
+			}
+
+			t.try(dst, fmt.Sprintf("fail(%q)", fmt.Sprintf("cannot assign to %s", v.LHS)), v.Span)
+
+			return nil
+		}
+
+		rhs, err := t.expr(v.RHS)
+		if err != nil {
+			return err
+		}
+
+		t.try(dst, fmt.Sprintf("i.Assign(%v, %v)", t.global(variable.Global), rhs), v.Span)
+
+		return nil
+	case token.Add:
+		method = "Add"
+	case token.Sub:
+		method = "Sub"
+	case token.Mul:
+		method = "Mul"
+	case token.Div:
+		method = "Div"
+	case token.Mod:
+		method = "Mod"
+	case token.Exp:
+		method = "Exp"
+	case token.Less:
+		method = "Less"
+	case token.Greater:
+		method = "Greater"
+	case token.Equal:
+		method = "Equal"
+	default:
+		return fmt.Errorf("cannot transpile binary operator: %s", v)
+	}
+
+	results, err := t.exprs(v.LHS, v.RHS)
+	if err != nil {
+		return err
+	}
+
+	t.try(dst, fmt.Sprintf("i.%v(%v, %v)", method, results[0], results[1]), v.Span)
+
+	return nil
+}
+
+// at returns the Go expression for the given span.
+func at(span ast.Span) string {
+	return fmt.Sprintf("at(%v, %v, %v, %v)", span.Line, span.Col, span.EndLine, span.EndCol)
+}
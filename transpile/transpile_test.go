@@ -0,0 +1,177 @@
+package transpile_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/polyscone/knight/ast"
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/lexer"
+	"github.com/polyscone/knight/optimize"
+	"github.com/polyscone/knight/parser"
+	"github.com/polyscone/knight/transpile"
+	"github.com/polyscone/knight/value"
+)
+
+func TestProgram(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip("skipping transpiled builds in short mode")
+	}
+
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("skipping transpiled builds without a go command")
+	}
+
+	tt := []struct {
+		name   string
+		source string
+		stdin  string
+		level  optimize.Level
+	}{
+		{"output", `OUTPUT "hello"`, "", optimize.None},
+		{"arithmetic", `OUTPUT + * 3 4 - 10 / 9 2`, "", optimize.None},
+		{"mod and exp", `; OUTPUT % 17 5 OUTPUT ^ 2 10`, "", optimize.None},
+		{"big literal", `; DUMP 123456789012345678901234567890 OUTPUT + 1 18446744073709551617`, "", optimize.None},
+		{"strings", `; OUTPUT + "foo" * "ab" 3 ; OUTPUT GET "hello" 1 3 ; OUTPUT SUBSTITUTE "hello" 0 1 "j" OUTPUT + LENGTH "four" ASCII 65`, "", optimize.None},
+		{"comparisons", `; OUTPUT ? 1 "1" ; OUTPUT < "a" "b" ; OUTPUT > 3 2 OUTPUT ! 0`, "", optimize.None},
+		{"and or", `; OUTPUT & 0 "no" ; OUTPUT & 1 "yes" ; OUTPUT | "" "rhs" OUTPUT | "lhs" 0`, "", optimize.None},
+		{"while", `; = n 0 ; WHILE < n 5 ; OUTPUT n = n + n 1 OUTPUT n`, "", optimize.None},
+		{"if", `; OUTPUT IF T "yes" "no" OUTPUT IF "" "yes" "no"`, "", optimize.None},
+		{"lists", `; = l + @ "abc" ; OUTPUT ^ l "-" ; OUTPUT [ l ; OUTPUT ] l ; DUMP + , 1 l OUTPUT LENGTH * l 2`, "", optimize.None},
+		{"dump", `; DUMP BLOCK 1 ; DUMP BLOCK + 1 2 ; DUMP NULL ; DUMP T DUMP "foo"`, "", optimize.None},
+		{"block auto call", `; = f BLOCK 3 ; = g BLOCK + f 1 OUTPUT g`, "", optimize.None},
+		{"call", `; = f BLOCK * x 2 ; = x 21 ; OUTPUT CALL BLOCK "literal" OUTPUT CALL f`, "", optimize.None},
+		{"recursion", `; = fib BLOCK IF < n 2 n + ; = n - n 1 fib ; = n - n 1 fib ; = n 10 OUTPUT fib`, "", optimize.None},
+		{"eval", `; = x 2 ; OUTPUT EVAL "+ x 1" ; EVAL "= y * x 10" OUTPUT y`, "", optimize.None},
+		{"eval calls compiled block", `; = f BLOCK + x 1 ; = x 2 OUTPUT EVAL "CALL f"`, "", optimize.None},
+		{"compiled code calls eval block", `; EVAL "= g BLOCK * 2 3" OUTPUT CALL g`, "", optimize.None},
+		{"prompt", `; OUTPUT + "> " PROMPT OUTPUT PROMPT`, "foo\nbar\n", optimize.None},
+		{"quit", `; OUTPUT "bye" QUIT 3`, "", optimize.None},
+		{"undefined variable", `; OUTPUT "before" OUTPUT + 1 x`, "", optimize.None},
+		{"runtime error in block", "; = f BLOCK\n  + 1 BLOCK 2\n; = g BLOCK ; f 0\nCALL g", "", optimize.None},
+		{"error in eval", `; = f BLOCK EVAL "/ 1 0" CALL f`, "", optimize.None},
+		{"max depth", `; = f BLOCK + 1 CALL f CALL f`, "", optimize.None},
+		{"simplified", `; : : OUTPUT "a" ; 1 ; IF T OUTPUT "b" OUTPUT "c" WHILE F OUTPUT "d"`, "", optimize.Simplify},
+		{"folded", `; OUTPUT + 1 * 2 3 ; = x + "a" "b" OUTPUT x`, "", optimize.Fold},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			want := interpret(t, tc.source, tc.stdin, tc.level)
+
+			program, _ := parse(t, tc.source, tc.level)
+			src, err := transpile.Program(program)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			file := filepath.Join(dir, "main.go")
+			if err := os.WriteFile(file, src, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			// The generated code imports this module's packages, so it's
+			// built from the module's root
+			bin := filepath.Join(dir, "main")
+			build := exec.Command("go", "build", "-o", bin, file)
+			build.Dir = ".."
+			if out, err := build.CombinedOutput(); err != nil {
+				t.Fatalf("building transpiled program: %v\n%s\n%s", err, out, src)
+			}
+
+			var stdout bytes.Buffer
+			cmd := exec.Command(bin)
+			cmd.Stdin = strings.NewReader(tc.stdin)
+			cmd.Stdout = &stdout
+
+			code := 0
+			if err := cmd.Run(); err != nil {
+				var exit *exec.ExitError
+				if !errors.As(err, &exit) {
+					t.Fatal(err)
+				}
+
+				code = exit.ExitCode()
+			}
+
+			got := result{stdout: stdout.String(), code: code}
+			if want != got {
+				t.Errorf("want %+v, got %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestProgramEmpty(t *testing.T) {
+	t.Parallel()
+
+	if _, err := transpile.Program(ast.Program{}); err == nil {
+		t.Error("want an error for an empty program, got nil")
+	}
+}
+
+// result is what running a program writes to stdout and its exit code.
+type result struct {
+	stdout string
+	code   int
+}
+
+func parse(t *testing.T, source string, level optimize.Level) (ast.Program, *value.GlobalStore) {
+	t.Helper()
+
+	g := value.NewGlobalStore()
+	program, err := parser.New(lexer.New()).ParseFile(g, "test.kn", strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return optimize.Program(program, level), g
+}
+
+// interpret runs the given program with the interpreter and returns the same
+// result that a transpiled program reports.
+func interpret(t *testing.T, source, stdin string, level optimize.Level) result {
+	t.Helper()
+
+	program, g := parse(t, source, level)
+
+	var stdout bytes.Buffer
+	i := interpreter.New(
+		g,
+		parser.New(lexer.New()),
+		interpreter.WithStdin(strings.NewReader(stdin)),
+		interpreter.WithStdout(&stdout),
+		interpreter.WithStderr(&bytes.Buffer{}),
+	)
+
+	_, err := i.Execute(program)
+	if err == nil {
+		return result{stdout: stdout.String()}
+	}
+
+	var exit *interpreter.ExitError
+	if errors.As(err, &exit) {
+		return result{stdout: stdout.String(), code: exit.Code}
+	}
+
+	fmt.Fprintln(&stdout, err)
+
+	var rerr *interpreter.RuntimeError
+	if errors.As(err, &rerr) {
+		fmt.Fprint(&stdout, "\nknight stack:\n", rerr.StackTrace())
+	}
+
+	return result{stdout: stdout.String(), code: 1}
+}
@@ -1,5 +1,7 @@
 package value
 
+import "math/big"
+
 // Atoi converts a string into an int according to the Knight spec.
 //
 // This function is used instead of something like fmt.Sscanf() because it
@@ -51,3 +53,45 @@ func Atoi(s string) int {
 
 	return num * mul
 }
+
+// maxAtoiLength is the length of the longest string that Atoi can always
+// convert without overflowing, which is 18 when an int has 64 bits, and 9 when
+// it has 32 bits.
+const maxAtoiLength = 9 + 9*int(^uint(0)>>63)
+
+// AtoiBig converts a string into a big.Int according to the Knight spec, in
+// the same way as Atoi, but the result is exact however many digits there are.
+func AtoiBig(s string) *big.Int {
+	start := 0
+	for start < len(s) && s[start] == ' ' {
+		start++
+	}
+
+	end := start
+	if end < len(s) && s[end] == '-' {
+		end++
+	}
+
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+
+	b, ok := new(big.Int).SetString(s[start:end], 10)
+	if !ok {
+		return new(big.Int)
+	}
+
+	return b
+}
+
+// atoi converts a string into an Int according to the Knight spec, interning
+// it in the heap.
+// Strings that are short enough to never overflow an int use Atoi, and longer
+// ones are converted exactly with AtoiBig.
+func (h *Heap) atoi(s string) *Int {
+	if len(s) <= maxAtoiLength {
+		return h.NewInt(Atoi(s))
+	}
+
+	return h.NewBigInt(AtoiBig(s))
+}
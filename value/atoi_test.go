@@ -44,3 +44,36 @@ func TestAtoi(t *testing.T) {
 		})
 	}
 }
+
+func TestAtoiBig(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name string
+		str  string
+		want string
+	}{
+		{"zero", "0", "0"},
+		{"empty string", "", "0"},
+		{"minus sign only", "-", "0"},
+		{"identifier only", "a", "0"},
+		{"positive integer", "123", "123"},
+		{"negative integer with leading spaces", "       -456", "-456"},
+		{"integer interrupted by identifier", "78a9", "78"},
+		{"negative integer interrupted by a second minus sign", "-78-9", "-78"},
+		{"minus sign after a space", "- 5", "0"},
+		{"huge", "  123456789012345678901234567890 ", "123456789012345678901234567890"},
+		{"huge negative", "-123456789012345678901234567890abc", "-123456789012345678901234567890"},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := value.AtoiBig(tc.str).String(); got != tc.want {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ type Block struct {
 // AsBool is only implemented here so that Block can be used as a value.
 // Any attempt to actually call this method will result in a panic, because a
 // block should only be used for its expression as an argument to CALL.
+// ToBool should be used when the value might be a block.
 func (b *Block) AsBool() *Bool {
 	panic("a block cannot be converted into a bool")
 }
@@ -22,6 +23,7 @@ func (b *Block) AsBool() *Bool {
 // AsInt is only implemented here so that Block can be used as a value.
 // Any attempt to actually call this method will result in a panic, because a
 // block should only be used for its expression as an argument to CALL.
+// ToInt should be used when the value might be a block.
 func (b *Block) AsInt() *Int {
 	panic("a block cannot be converted into an int")
 }
@@ -29,10 +31,19 @@ func (b *Block) AsInt() *Int {
 // AsString is only implemented here so that Block can be used as a value.
 // Any attempt to actually call this method will result in a panic, because a
 // block should only be used for its expression as an argument to CALL.
+// ToString should be used when the value might be a block.
 func (b *Block) AsString() *String {
 	panic("a block cannot be converted into a string")
 }
 
+// AsList is only implemented here so that Block can be used as a value.
+// Any attempt to actually call this method will result in a panic, because a
+// block should only be used for its expression as an argument to CALL.
+// ToList should be used when the value might be a block.
+func (b *Block) AsList() *List {
+	panic("a block cannot be converted into a list")
+}
+
 // AsExpr returns the wrapped Expression, which should be used as an argument
 // to CALL.
 func (b *Block) AsExpr() Expression {
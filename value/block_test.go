@@ -1,6 +1,7 @@
 package value_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/polyscone/knight/ast"
@@ -46,6 +47,23 @@ func TestBlockConversions(t *testing.T) {
 		value.NewBlock(value.NewInt(0)).AsString()
 	})
 
+	t.Run("checked conversions return errors", func(t *testing.T) {
+		t.Parallel()
+
+		block := value.NewBlock(value.NewInt(0))
+
+		var err *value.ConversionError
+		if _, got := value.ToBool(block); !errors.As(got, &err) {
+			t.Errorf("want ToBool to return *value.ConversionError, got %v", got)
+		}
+		if _, got := value.ToInt(block); !errors.As(got, &err) {
+			t.Errorf("want ToInt to return *value.ConversionError, got %v", got)
+		}
+		if _, got := value.ToString(block); !errors.As(got, &err) {
+			t.Errorf("want ToString to return *value.ConversionError, got %v", got)
+		}
+	})
+
 	t.Run("as expr", func(t *testing.T) {
 		t.Parallel()
 
@@ -41,6 +41,16 @@ func (b *Bool) AsString() *String {
 	return falseString
 }
 
+// AsList converts the caller to a runtime List value that holds the caller if
+// it's true, or to an empty list otherwise.
+func (b *Bool) AsList() *List {
+	if b.Value {
+		return NewList([]Value{b})
+	}
+
+	return emptyList
+}
+
 // AsExpr returns the value itself as an Expression interface implementation.
 func (b *Bool) AsExpr() Expression {
 	return b
@@ -0,0 +1,61 @@
+package value
+
+import "fmt"
+
+// Cache identifies one of the interning caches in a Heap.
+type Cache int
+
+// Interning caches.
+const (
+	// CacheStrings interns strings by their contents.
+	CacheStrings Cache = iota
+
+	// CacheConcats remembers the result of concatenating two interned strings.
+	CacheConcats
+
+	// CacheSubstrings remembers the substrings taken from interned strings.
+	CacheSubstrings
+
+	// CacheIntStrings remembers the string conversions of integers.
+	CacheIntStrings
+
+	// CacheInts interns integers between MinInternInt and MaxInternInt.
+	CacheInts
+
+	numCaches
+)
+
+// String returns the name of the cache.
+func (c Cache) String() string {
+	switch c {
+	case CacheStrings:
+		return "strings"
+	case CacheConcats:
+		return "concats"
+	case CacheSubstrings:
+		return "substrings"
+	case CacheIntStrings:
+		return "int strings"
+	case CacheInts:
+		return "ints"
+	}
+
+	return fmt.Sprintf("cache(%d)", int(c))
+}
+
+// DefaultCacheLimit is the maximum number of entries each cache in a new Heap
+// holds unless it's changed with SetCacheLimit.
+const DefaultCacheLimit = 1 << 16
+
+// CacheStats describes the size and effectiveness of a cache.
+//
+// Every cache is a bounded map that evicts its least recently used entries
+// generationally, so entries that are still in use survive.
+type CacheStats struct {
+	Cache     Cache
+	Len       int
+	Limit     int
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
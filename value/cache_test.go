@@ -0,0 +1,105 @@
+package value_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/polyscone/knight/value"
+)
+
+// The default heap is shared by the whole package, so these tests don't run in
+// parallel and they restore the default limits when they're done.
+
+func TestCacheEviction(t *testing.T) {
+	defer value.SetCacheLimit(value.CacheStrings, value.DefaultCacheLimit)
+
+	value.ClearCaches()
+	value.SetCacheLimit(value.CacheStrings, 4)
+
+	if got := value.CacheLimit(value.CacheStrings); got != 4 {
+		t.Fatalf("want limit 4, got %v", got)
+	}
+
+	kept := value.NewString("kept")
+	for n := 0; n < 100; n++ {
+		value.NewString("evicted " + strconv.Itoa(n))
+
+		// Using a string keeps it in the cache
+		if got := value.NewString("kept"); got != kept {
+			t.Fatalf("want %p, got %p", kept, got)
+		}
+	}
+
+	stats := value.Stats()[value.CacheStrings]
+	if stats.Cache != value.CacheStrings {
+		t.Errorf("want stats for %v, got %v", value.CacheStrings, stats.Cache)
+	}
+
+	if stats.Len > 4 {
+		t.Errorf("want at most 4 entries, got %v", stats.Len)
+	}
+
+	if stats.Evictions == 0 {
+		t.Error("want evictions, got none")
+	}
+
+	if stats.Hits < 100 {
+		t.Errorf("want at least 100 hits, got %v", stats.Hits)
+	}
+
+	// Evicted strings are no longer pointer-equal to new ones, but they're
+	// still equal
+	evicted := value.NewString("evicted 0")
+	if !value.Equal(evicted, value.NewUniqueString("evicted 0")) {
+		t.Errorf("want %v to equal an evicted copy", evicted)
+	}
+}
+
+func TestCacheOddLimit(t *testing.T) {
+	defer value.SetCacheLimit(value.CacheStrings, value.DefaultCacheLimit)
+
+	value.ClearCaches()
+	value.SetCacheLimit(value.CacheStrings, 3)
+
+	if got := value.CacheLimit(value.CacheStrings); got != 4 {
+		t.Fatalf("want limit to be rounded up to 4, got %v", got)
+	}
+
+	for n := 0; n < 100; n++ {
+		value.NewString("odd " + strconv.Itoa(n))
+	}
+
+	stats := value.Stats()[value.CacheStrings]
+	if stats.Limit != 4 {
+		t.Errorf("want a stats limit of 4, got %v", stats.Limit)
+	}
+
+	if stats.Len > stats.Limit {
+		t.Errorf("want at most %v entries, got %v", stats.Limit, stats.Len)
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	defer value.SetCacheLimit(value.CacheInts, value.DefaultCacheLimit)
+
+	value.SetCacheLimit(value.CacheInts, 0)
+
+	a, b := value.NewInt(7), value.NewInt(7)
+	if a == b {
+		t.Errorf("want distinct ints when the cache is disabled, got %p twice", a)
+	}
+
+	if !value.Equal(a, b) {
+		t.Errorf("want %v to equal %v", a, b)
+	}
+
+	if got := value.Stats()[value.CacheInts].Len; got != 0 {
+		t.Errorf("want an empty cache, got %v entries", got)
+	}
+
+	value.SetCacheLimit(value.CacheInts, value.DefaultCacheLimit)
+
+	if a, b := value.NewInt(7), value.NewInt(7); a != b {
+		t.Errorf("want interned ints to be pointer-equal, got %p and %p", a, b)
+	}
+}
@@ -3,14 +3,17 @@ package value
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/polyscone/knight/ast"
 	"github.com/polyscone/knight/options"
 )
 
 // Global represents a global name/value pair.
+// Slot is the global's index in the GlobalStore that declared it.
 type Global struct {
 	Name  string
+	Slot  int
 	Value Value
 }
 
@@ -36,36 +39,116 @@ func (g Global) ASTString(style ast.Style) string {
 	}
 }
 
+// Variable is an AST node that refers to a Global from a specific place in the
+// source code.
+// Every reference to the same name shares the same Global, but each reference
+// has its own Variable so that it can report where it came from.
+type Variable struct {
+	Global *Global
+	Span   ast.Span
+}
+
+// String prints a string form of Variable as an s-expression for testing.
+func (v *Variable) String() string {
+	return v.Global.String()
+}
+
+// ASTString returns a string representation of the AST in the requested style.
+func (v *Variable) ASTString(style ast.Style) string {
+	return v.Global.ASTString(style)
+}
+
+// Location returns the region of source code the variable was parsed from.
+func (v *Variable) Location() ast.Span {
+	return v.Span
+}
+
+// NewVariable returns a Variable that refers to the given Global.
+func NewVariable(g *Global, span ast.Span) *Variable {
+	return &Variable{Global: g, Span: span}
+}
+
 // GlobalStore holds the state for a group of global name/value pairs.
 // Globals from one store should not be mixed with another.
+//
+// Every global is assigned a dense integer slot in the order that it was first
+// declared, which is its index in the store.
+// Lookups never need to take a lock; only declaring a new global does.
 type GlobalStore struct {
-	sync.Mutex
-	data map[string]*Global
+	mu    sync.Mutex
+	names sync.Map
+
+	// slots holds a []*Global that is only ever appended to.
+	// Readers that loaded a shorter slice are unaffected by an append because
+	// the elements they can see are never written to again.
+	slots atomic.Value
 }
 
 // New returns a Global with the given name.
-// If the global doesn't exist yet then it is created.
+// If the global doesn't exist yet then it is created and assigned the next
+// free slot.
 //
 // Globals are always cached by name in the given store, so calling this
 // function multiple times with the same store and name will return the
 // same global object.
 func (gs *GlobalStore) New(name string) *Global {
+	if g, ok := gs.Lookup(name); ok {
+		return g
+	}
+
 	if !options.Reckless {
-		gs.Lock()
-		defer gs.Unlock()
+		gs.mu.Lock()
+		defer gs.mu.Unlock()
 	}
 
-	if v, ok := gs.data[name]; ok {
-		return v
+	// Another goroutine might have declared the global while we were waiting
+	// for the lock
+	if g, ok := gs.Lookup(name); ok {
+		return g
 	}
 
-	v := Global{Name: name}
+	slots := gs.All()
+	g := &Global{Name: name, Slot: len(slots)}
 
-	gs.data[name] = &v
+	gs.slots.Store(append(slots, g))
+	gs.names.Store(name, g)
 
-	return &v
+	return g
 }
 
+// Lookup returns the Global with the given name if it has been declared.
+func (gs *GlobalStore) Lookup(name string) (*Global, bool) {
+	g, ok := gs.names.Load(name)
+	if !ok {
+		return nil, false
+	}
+
+	//nolint:forcetypeassert // only globals are stored
+	return g.(*Global), true
+}
+
+// Slot returns the Global in the given slot.
+func (gs *GlobalStore) Slot(slot int) *Global {
+	return gs.All()[slot]
+}
+
+// Len returns the number of globals that have been declared.
+func (gs *GlobalStore) Len() int {
+	return len(gs.All())
+}
+
+// All returns every declared Global in slot order, which is the order they
+// were declared in.
+// The returned slice must not be modified.
+func (gs *GlobalStore) All() []*Global {
+	//nolint:forcetypeassert // the slots are always set by NewGlobalStore
+	return gs.slots.Load().([]*Global)
+}
+
+// NewGlobalStore returns an empty GlobalStore.
 func NewGlobalStore() *GlobalStore {
-	return &GlobalStore{data: make(map[string]*Global)}
+	var gs GlobalStore
+	gs.slots.Store([]*Global(nil))
+
+	return &gs
 }
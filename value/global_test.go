@@ -1,6 +1,8 @@
 package value_test
 
 import (
+	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/polyscone/knight/value"
@@ -35,3 +37,71 @@ func TestGlobalDump(t *testing.T) {
 		})
 	}
 }
+
+func TestGlobalStoreSlots(t *testing.T) {
+	t.Parallel()
+
+	globals := value.NewGlobalStore()
+
+	names := []string{"b", "a", "c", "a", "b", "d"}
+	for _, name := range names {
+		globals.New(name)
+	}
+
+	want := []string{"b", "a", "c", "d"}
+	if got := globals.Len(); got != len(want) {
+		t.Fatalf("want %v globals, got %v", len(want), got)
+	}
+
+	for slot, g := range globals.All() {
+		if g.Name != want[slot] {
+			t.Errorf("want global %q in slot %v, got %q", want[slot], slot, g.Name)
+		}
+
+		if g.Slot != slot {
+			t.Errorf("want %q to have slot %v, got %v", g.Name, slot, g.Slot)
+		}
+
+		if got := globals.Slot(slot); got != g {
+			t.Errorf("want slot %v to be %v, got %v", slot, g, got)
+		}
+
+		if got, ok := globals.Lookup(g.Name); !ok || got != g {
+			t.Errorf("want lookup of %q to be %v, got %v", g.Name, g, got)
+		}
+	}
+
+	if g, ok := globals.Lookup("missing"); ok {
+		t.Errorf("want no global, got %v", g)
+	}
+}
+
+func TestGlobalStoreConcurrentNew(t *testing.T) {
+	t.Parallel()
+
+	globals := value.NewGlobalStore()
+
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 100; i++ {
+				globals.New(strconv.Itoa(i))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := globals.Len(); got != 100 {
+		t.Fatalf("want 100 globals, got %v", got)
+	}
+
+	for slot, g := range globals.All() {
+		if g.Slot != slot {
+			t.Errorf("want %q to have slot %v, got %v", g.Name, slot, g.Slot)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package value
+
+import "github.com/polyscone/knight/internal/gencache"
+
+// Heap owns a set of interning caches.
+//
+// Values that are created through a heap are only interned in that heap, so
+// programs that each use their own heap don't contend on the same caches, and
+// all of the memory held by a heap's caches is released along with the heap.
+// Values from different heaps can still be mixed freely; they just won't be
+// pointer-equal to each other.
+//
+// The package level constructors, like NewString and NewInt, use a default
+// heap that's shared by the whole process.
+//
+// A heap is safe for concurrent use, except in reckless builds.
+type Heap struct {
+	strs    *gencache.Cache
+	concats *gencache.Cache
+	substrs *gencache.Cache
+	intStrs *gencache.Cache
+	ints    *gencache.Cache
+	caches  [numCaches]*gencache.Cache
+}
+
+// defaultHeap is used by the package level constructors.
+var defaultHeap = NewHeap()
+
+// NewHeap returns a new Heap with empty caches that each hold up to
+// DefaultCacheLimit entries.
+func NewHeap() *Heap {
+	h := &Heap{
+		strs:    gencache.New(DefaultCacheLimit),
+		concats: gencache.New(DefaultCacheLimit),
+		substrs: gencache.New(DefaultCacheLimit),
+		intStrs: gencache.New(DefaultCacheLimit),
+		ints:    gencache.New(DefaultCacheLimit),
+	}
+
+	h.caches = [numCaches]*gencache.Cache{h.strs, h.concats, h.substrs, h.intStrs, h.ints}
+
+	return h
+}
+
+// DefaultHeap returns the heap that's used by the package level constructors.
+func DefaultHeap() *Heap {
+	return defaultHeap
+}
+
+// ToInt works like the package level ToInt function, but any Int that has to
+// be created by the conversion is interned in the heap.
+func (h *Heap) ToInt(v Value) (*Int, error) {
+	switch v := v.(type) {
+	case *String:
+		return h.atoi(v.Value()), nil
+	case *List:
+		return h.NewInt(v.Len()), nil
+	}
+
+	return ToInt(v)
+}
+
+// ToString works like the package level ToString function, but any String
+// that has to be created by the conversion is interned in the heap.
+func (h *Heap) ToString(v Value) (*String, error) {
+	switch v := v.(type) {
+	case *Int:
+		return h.intString(v), nil
+	case *List:
+		return h.Join(v, "\n")
+	}
+
+	return ToString(v)
+}
+
+// ToList works like the package level ToList function, but any values that
+// have to be created by the conversion are interned in the heap.
+func (h *Heap) ToList(v Value) (*List, error) {
+	switch v := v.(type) {
+	case *Int:
+		return h.digits(v), nil
+	case *String:
+		return h.chars(v), nil
+	}
+
+	return ToList(v)
+}
+
+// SetCacheLimit sets the maximum number of entries the given cache can hold,
+// evicting entries straight away if it's already over the new limit.
+// Limits are rounded up to an even number, and a limit of 0 disables the cache
+// entirely, which means values will no longer be interned.
+//
+// Values that were interned before being evicted remain valid, and Equal still
+// compares them correctly, but they may no longer be pointer-equal to newly
+// created values with the same contents.
+func (h *Heap) SetCacheLimit(c Cache, limit int) {
+	h.caches[c].SetLimit(limit)
+}
+
+// CacheLimit returns the maximum number of entries the given cache can hold.
+func (h *Heap) CacheLimit(c Cache) int {
+	return h.caches[c].Stats().Limit
+}
+
+// Clear evicts every entry from every cache without changing their limits or
+// resetting their stats.
+func (h *Heap) Clear() {
+	for _, c := range h.caches {
+		c.Clear()
+	}
+}
+
+// Stats returns the current stats for every cache, indexed by Cache.
+func (h *Heap) Stats() []CacheStats {
+	stats := make([]CacheStats, len(h.caches))
+	for n, c := range h.caches {
+		s := c.Stats()
+		stats[n] = CacheStats{
+			Cache:     Cache(n),
+			Len:       s.Len,
+			Limit:     s.Limit,
+			Hits:      s.Hits,
+			Misses:    s.Misses,
+			Evictions: s.Evictions,
+		}
+	}
+
+	return stats
+}
+
+// SetCacheLimit calls SetCacheLimit on the default heap.
+func SetCacheLimit(c Cache, limit int) {
+	defaultHeap.SetCacheLimit(c, limit)
+}
+
+// CacheLimit calls CacheLimit on the default heap.
+func CacheLimit(c Cache) int {
+	return defaultHeap.CacheLimit(c)
+}
+
+// ClearCaches calls Clear on the default heap.
+func ClearCaches() {
+	defaultHeap.Clear()
+}
+
+// Stats calls Stats on the default heap.
+func Stats() []CacheStats {
+	return defaultHeap.Stats()
+}
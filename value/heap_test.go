@@ -0,0 +1,78 @@
+package value_test
+
+import (
+	"testing"
+
+	"github.com/polyscone/knight/value"
+)
+
+func TestHeapIsolation(t *testing.T) {
+	t.Parallel()
+
+	a, b := value.NewHeap(), value.NewHeap()
+
+	if x, y := a.NewString("foo"), a.NewString("foo"); x != y {
+		t.Errorf("want strings from the same heap to be pointer-equal, got %p and %p", x, y)
+	}
+
+	x, y := a.NewString("foo"), b.NewString("foo")
+	if x == y {
+		t.Errorf("want strings from different heaps to be distinct, got %p twice", x)
+	}
+
+	if !value.Equal(x, y) {
+		t.Errorf("want %v to equal %v", x, y)
+	}
+
+	// Strings from another heap can still be used as cache keys
+	concat := a.NewConcatString(x, y)
+	if got := a.NewConcatString(x, y); got != concat {
+		t.Errorf("want %p, got %p", concat, got)
+	}
+
+	if want, got := "foofoo", concat.Value(); want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	if got := b.Stats()[value.CacheConcats].Misses; got != 0 {
+		t.Errorf("want no concat misses in the other heap, got %v", got)
+	}
+
+	a.Clear()
+
+	if got := a.Stats()[value.CacheStrings].Len; got != 0 {
+		t.Errorf("want an empty cache after clearing, got %v entries", got)
+	}
+
+	if got := b.Stats()[value.CacheStrings].Len; got != 1 {
+		t.Errorf("want the other heap to keep 1 entry, got %v", got)
+	}
+}
+
+func TestHeapConversions(t *testing.T) {
+	t.Parallel()
+
+	h := value.NewHeap()
+
+	i, err := h.ToInt(h.NewString("12"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := h.NewInt(12); got != i {
+		t.Errorf("want %p, got %p", i, got)
+	}
+
+	s, err := h.ToString(value.NewInt(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := h.NewString("12"); got != s {
+		t.Errorf("want %p, got %p", s, got)
+	}
+
+	if _, err := h.ToString(value.NewBlock(value.NewNull())); err == nil {
+		t.Error("want an error converting a block, got nil")
+	}
+}
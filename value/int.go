@@ -3,11 +3,11 @@ package value
 import (
 	"fmt"
 	"math"
+	"math/big"
 	"strconv"
-	"sync"
 
 	"github.com/polyscone/knight/ast"
-	"github.com/polyscone/knight/options"
+	"github.com/polyscone/knight/internal/gencache"
 )
 
 // These values define the range of ints to intern.
@@ -24,14 +24,47 @@ var (
 	one  = NewInt(1)
 )
 
-var ints = struct {
-	sync.Mutex
-	data map[int]*Int
-}{data: make(map[int]*Int)}
-
 // Int represents a runtime integer value.
+//
+// Integers that don't fit in an int are held exactly as a big.Int, in which
+// case Value is clamped to the nearest int, so code that only expects small
+// integers, like string indexes, still sees a number that's too large.
 type Int struct {
 	Value int
+
+	big *big.Int
+}
+
+// IsBig reports whether the integer is too large to fit in an int.
+func (i *Int) IsBig() bool {
+	return i.big != nil
+}
+
+// Big returns the exact value of the integer as a big.Int.
+// The returned value must not be modified.
+func (i *Int) Big() *big.Int {
+	if i.big != nil {
+		return i.big
+	}
+
+	return big.NewInt(int64(i.Value))
+}
+
+// Cmp compares the caller with the given Int and returns -1, 0, or 1 if the
+// caller is less than, equal to, or greater than it.
+func (i *Int) Cmp(j *Int) int {
+	if i.big != nil || j.big != nil {
+		return i.Big().Cmp(j.Big())
+	}
+
+	switch {
+	case i.Value < j.Value:
+		return -1
+	case i.Value > j.Value:
+		return 1
+	}
+
+	return 0
 }
 
 // AsBool converts the caller to a false Bool runtime value if the caller's
@@ -47,7 +80,13 @@ func (i *Int) AsInt() *Int {
 
 // AsString converts the caller to a runtime String representation of its value.
 func (i *Int) AsString() *String {
-	return NewIntString(i.Value)
+	return defaultHeap.intString(i)
+}
+
+// AsList converts the caller to a runtime List value that holds each of its
+// digits, which are negative if the caller is.
+func (i *Int) AsList() *List {
+	return defaultHeap.digits(i)
 }
 
 // AsExpr returns the value itself as an Expression interface implementation.
@@ -57,7 +96,7 @@ func (i *Int) AsExpr() Expression {
 
 // Dump prints a string form of Int for testing.
 func (i *Int) Dump() string {
-	return fmt.Sprintf("Number(%v)", i.Value)
+	return fmt.Sprintf("Number(%v)", i.ASTString(ast.StyleSexpr))
 }
 
 // String prints a string form of the Int as an s-expression for testing.
@@ -68,29 +107,29 @@ func (i *Int) String() string {
 
 // ASTString returns a string representation of the AST in the requested style.
 func (i *Int) ASTString(style ast.Style) string {
+	if i.big != nil {
+		return i.big.String()
+	}
+
 	return strconv.Itoa(i.Value)
 }
 
 // NewInt will return a runtime Int value that wraps the given int.
 func NewInt(i int) *Int {
+	return defaultHeap.NewInt(i)
+}
+
+// NewInt will return a runtime Int value that wraps the given int, interning
+// it in the heap if it's between MinInternInt and MaxInternInt.
+func (h *Heap) NewInt(i int) *Int {
 	if i < MinInternInt || i > MaxInternInt {
 		return NewUniqueInt(i)
 	}
 
-	if !options.Reckless {
-		ints.Lock()
-		defer ints.Unlock()
-	}
-
-	if v, ok := ints.data[i]; ok {
-		return v
-	}
-
-	v := Int{Value: i}
-
-	ints.data[i] = &v
-
-	return &v
+	//nolint:forcetypeassert // the cache only holds ints
+	return h.ints.LoadOrCreate(gencache.Key{Ints: [3]int{i}}, func() interface{} {
+		return &Int{Value: i}
+	}).(*Int)
 }
 
 // NewUniqueInt will return a runtime Int value that wraps the given int, but the
@@ -98,3 +137,44 @@ func NewInt(i int) *Int {
 func NewUniqueInt(i int) *Int {
 	return &Int{Value: i}
 }
+
+// NewBigInt will return a runtime Int value that holds the given big.Int,
+// which must not be modified afterwards.
+func NewBigInt(b *big.Int) *Int {
+	return defaultHeap.NewBigInt(b)
+}
+
+// NewBigInt works like NewInt, but takes a big.Int, which must not be modified
+// afterwards.
+// Integers that fit in an int are held as an int, and are interned in the heap
+// in the same way as NewInt.
+func (h *Heap) NewBigInt(b *big.Int) *Int {
+	if b.IsInt64() {
+		if n := b.Int64(); int64(int(n)) == n {
+			return h.NewInt(int(n))
+		}
+	}
+
+	var clamped int64 = math.MaxInt64
+	if b.Sign() < 0 {
+		clamped = math.MinInt64
+	}
+
+	return &Int{Value: clampInt(clamped), big: b}
+}
+
+// clampInt converts the given int64 to an int, clamping it to the range of an
+// int on platforms where an int has less than 64 bits.
+func clampInt(n int64) int {
+	const maxInt = int(^uint(0) >> 1)
+	const minInt = -maxInt - 1
+
+	switch {
+	case n > int64(maxInt):
+		return maxInt
+	case n < int64(minInt):
+		return minInt
+	}
+
+	return int(n)
+}
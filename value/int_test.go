@@ -1,6 +1,7 @@
 package value_test
 
 import (
+	"math"
 	"testing"
 
 	"github.com/polyscone/knight/value"
@@ -19,6 +20,8 @@ func TestIntConversions(t *testing.T) {
 		{"zero", value.NewInt(0), value.NewBool(false), value.NewInt(0), value.NewString("0")},
 		{"one", value.NewInt(1), value.NewBool(true), value.NewInt(1), value.NewString("1")},
 		{"negative", value.NewInt(-123), value.NewBool(true), value.NewInt(-123), value.NewString("-123")},
+		{"big", bigInt("123456789012345678901234567890"), value.NewBool(true), bigInt("123456789012345678901234567890"), value.NewString("123456789012345678901234567890")},
+		{"negative big", bigInt("-98765432109876543210"), value.NewBool(true), bigInt("-98765432109876543210"), value.NewString("-98765432109876543210")},
 	}
 	for _, tc := range tt {
 		tc := tc
@@ -52,6 +55,7 @@ func TestIntDump(t *testing.T) {
 		{"zero", value.NewInt(0), "Number(0)"},
 		{"one", value.NewInt(1), "Number(1)"},
 		{"negative", value.NewInt(-123), "Number(-123)"},
+		{"big", bigInt("123456789012345678901234567890"), "Number(123456789012345678901234567890)"},
 	}
 	for _, tc := range tt {
 		tc := tc
@@ -65,3 +69,97 @@ func TestIntDump(t *testing.T) {
 		})
 	}
 }
+
+func TestBigInt(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name      string
+		str       string
+		wantBig   bool
+		wantValue int
+	}{
+		{"small", "123", false, 123},
+		{"max int", "9223372036854775807", false, math.MaxInt64},
+		{"min int", "-9223372036854775808", false, math.MinInt64},
+		{"just over max int", "9223372036854775808", true, math.MaxInt64},
+		{"just under min int", "-9223372036854775809", true, math.MinInt64},
+		{"huge", "123456789012345678901234567890", true, math.MaxInt64},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := bigInt(tc.str)
+
+			if got.IsBig() != tc.wantBig {
+				t.Errorf("want big %v, got %v", tc.wantBig, got.IsBig())
+			}
+
+			// Integers that aren't big have their Value clamped
+			if got.Value != tc.wantValue {
+				t.Errorf("want value %v, got %v", tc.wantValue, got.Value)
+			}
+
+			if want := tc.str; got.Big().String() != want {
+				t.Errorf("want %v, got %v", want, got.Big())
+			}
+		})
+	}
+}
+
+func TestBigIntInterned(t *testing.T) {
+	t.Parallel()
+
+	// Big integers that fit in an int are interned like any other int
+	if want, got := value.NewInt(42), bigInt("42"); want != got {
+		t.Errorf("want %v (%p), got %v (%p)", want, want, got, got)
+	}
+}
+
+func TestIntCmp(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name     string
+		lhs, rhs *value.Int
+		want     int
+	}{
+		{"small less", value.NewInt(1), value.NewInt(2), -1},
+		{"small equal", value.NewInt(2), value.NewInt(2), 0},
+		{"small greater", value.NewInt(3), value.NewInt(2), 1},
+		{"small less than big", value.NewInt(3), bigInt("99999999999999999999"), -1},
+		{"small greater than negative big", value.NewInt(3), bigInt("-99999999999999999999"), 1},
+		{"big equal", bigInt("99999999999999999999"), bigInt("99999999999999999999"), 0},
+		{"big greater", bigInt("99999999999999999999"), bigInt("99999999999999999998"), 1},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.lhs.Cmp(tc.rhs); got != tc.want {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestBigIntList(t *testing.T) {
+	t.Parallel()
+
+	want := list(value.NewInt(-1), value.NewInt(-2), value.NewInt(-3), value.NewInt(-4), value.NewInt(-5),
+		value.NewInt(-6), value.NewInt(-7), value.NewInt(-8), value.NewInt(-9), value.NewInt(0),
+		value.NewInt(-1), value.NewInt(-2), value.NewInt(-3), value.NewInt(-4), value.NewInt(-5),
+		value.NewInt(-6), value.NewInt(-7), value.NewInt(-8), value.NewInt(-9), value.NewInt(0))
+	if got := bigInt("-12345678901234567890").AsList(); !value.Equal(want, got) {
+		t.Errorf("want %v (%p), got %v (%p)", want, want, got, got)
+	}
+}
+
+func bigInt(s string) *value.Int {
+	return value.NewBigInt(value.AtoiBig(s))
+}
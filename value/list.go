@@ -0,0 +1,172 @@
+package value
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/polyscone/knight/ast"
+)
+
+var emptyList = &List{}
+
+// List represents a runtime list value.
+//
+// Lists are immutable, so the slice of values they hold must never be
+// modified, which means lists can share the slices they're created from
+// without copying them.
+type List struct {
+	Value []Value
+}
+
+// Len returns the number of values in the list.
+func (l *List) Len() int {
+	return len(l.Value)
+}
+
+// AsBool converts the caller to a false Bool runtime value if the caller is an
+// empty list, or to a true Bool value otherwise.
+func (l *List) AsBool() *Bool {
+	return NewBool(len(l.Value) != 0)
+}
+
+// AsInt converts the caller to a runtime Int value that holds its length.
+func (l *List) AsInt() *Int {
+	return NewInt(len(l.Value))
+}
+
+// AsString converts the caller to a runtime String value by converting each of
+// its values to a string and joining them with newlines.
+// This method will panic if the list contains a block, so ToString should be
+// used when that's possible.
+func (l *List) AsString() *String {
+	return NewString(l.join(func(v Value) string { return v.AsString().Value() }, "\n"))
+}
+
+// AsList returns the caller without modification.
+func (l *List) AsList() *List {
+	return l
+}
+
+// AsExpr returns the value itself as an Expression interface implementation.
+func (l *List) AsExpr() Expression {
+	return l
+}
+
+// Dump prints a string form of List for testing.
+func (l *List) Dump() string {
+	return "List(" + l.join(Value.Dump, ", ") + ")"
+}
+
+// String prints a string form of the List as an s-expression for testing.
+// The AsString method should be used to convert a value to a runtime String.
+func (l *List) String() string {
+	return l.ASTString(ast.StyleSexpr)
+}
+
+// ASTString returns a string representation of the AST in the requested style.
+func (l *List) ASTString(style ast.Style) string {
+	values := l.join(func(v Value) string { return " " + v.ASTString(style) }, "")
+
+	switch style {
+	case ast.StyleSexpr:
+		return fmt.Sprintf("(list%v)", values)
+	default:
+		return fmt.Sprintf("list%v", values)
+	}
+}
+
+// join converts each of the list's values to a string with the given function,
+// and joins them with the given separator.
+func (l *List) join(f func(Value) string, sep string) string {
+	strs := make([]string, len(l.Value))
+	for n, v := range l.Value {
+		strs[n] = f(v)
+	}
+
+	return strings.Join(strs, sep)
+}
+
+// Join converts each of the list's values to a string and joins them with the
+// given separator.
+// Like ToString it returns an error instead of panicking if any of the list's
+// values can't be converted.
+func (l *List) Join(sep string) (*String, error) {
+	return defaultHeap.Join(l, sep)
+}
+
+// Join works like List.Join, but any strings that have to be created are
+// interned in the heap.
+func (h *Heap) Join(l *List, sep string) (*String, error) {
+	strs := make([]string, len(l.Value))
+	for n, v := range l.Value {
+		s, err := h.ToString(v)
+		if err != nil {
+			return nil, err
+		}
+
+		strs[n] = s.Value()
+	}
+
+	return h.NewString(strings.Join(strs, sep)), nil
+}
+
+// digits returns a list of the digits of the given Int, interning them in the
+// heap.
+func (h *Heap) digits(n *Int) *List {
+	if n.big != nil {
+		s := n.big.String()
+
+		sign := 1
+		if s[0] == '-' {
+			s, sign = s[1:], -1
+		}
+
+		digits := make([]Value, len(s))
+		for i := range s {
+			digits[i] = h.NewInt(sign * int(s[i]-'0'))
+		}
+
+		return NewList(digits)
+	}
+
+	i := n.Value
+	if i == 0 {
+		return NewList([]Value{h.NewInt(0)})
+	}
+
+	var digits []Value
+	for ; i != 0; i /= 10 {
+		digits = append(digits, h.NewInt(i%10))
+	}
+
+	for lo, hi := 0, len(digits)-1; lo < hi; lo, hi = lo+1, hi-1 {
+		digits[lo], digits[hi] = digits[hi], digits[lo]
+	}
+
+	return NewList(digits)
+}
+
+// chars returns a list of the bytes in the given string as strings, interning
+// them in the heap.
+// The string is only flattened once, so ropes are split in linear time.
+func (h *Heap) chars(s *String) *List {
+	value := s.Value()
+
+	chars := make([]Value, len(value))
+	for n := range chars {
+		chars[n] = h.NewString(value[n : n+1])
+	}
+
+	return NewList(chars)
+}
+
+// NewList will return a runtime List value that holds the given values.
+// The slice is used by the list directly, so it must not be modified after
+// the list has been created.
+func NewList(values []Value) *List {
+	if len(values) == 0 {
+		return emptyList
+	}
+
+	return &List{Value: values}
+}
@@ -0,0 +1,150 @@
+package value_test
+
+import (
+	"testing"
+
+	"github.com/polyscone/knight/value"
+)
+
+func list(values ...value.Value) *value.List {
+	return value.NewList(values)
+}
+
+// chars returns a list of the bytes in the given string as strings.
+func chars(s string) *value.List {
+	values := make([]value.Value, len(s))
+	for n := range values {
+		values[n] = value.NewString(s[n : n+1])
+	}
+
+	return value.NewList(values)
+}
+
+func TestListConversions(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name       string
+		value      value.Value
+		wantBool   value.Value
+		wantInt    value.Value
+		wantString value.Value
+	}{
+		{"empty", value.NewList(nil), value.NewBool(false), value.NewInt(0), value.NewString("")},
+		{"one value", list(value.NewInt(1)), value.NewBool(true), value.NewInt(1), value.NewString("1")},
+		{"many values", list(value.NewString("a"), value.NewBool(true), value.NewNull()), value.NewBool(true), value.NewInt(3), value.NewString("a\ntrue\nnull")},
+		{"nested", list(list(value.NewInt(1), value.NewInt(2)), value.NewInt(3)), value.NewBool(true), value.NewInt(2), value.NewString("1\n2\n3")},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.value.AsBool(); !value.Equal(got, tc.wantBool) {
+				t.Errorf("want %v (%p), got %v (%p)", tc.wantBool, tc.wantBool, got, got)
+			}
+
+			if got := tc.value.AsInt(); !value.Equal(got, tc.wantInt) {
+				t.Errorf("want %v (%p), got %v (%p)", tc.wantInt, tc.wantInt, got, got)
+			}
+
+			if got := tc.value.AsString(); !value.Equal(got, tc.wantString) {
+				t.Errorf("want %v (%p), got %v (%p)", tc.wantString, tc.wantString, got, got)
+			}
+
+			if got, err := value.ToString(tc.value); err != nil || !value.Equal(got, tc.wantString) {
+				t.Errorf("want %v, got %v (%v)", tc.wantString, got, err)
+			}
+
+			if got := tc.value.AsList(); got != tc.value {
+				t.Errorf("want %v (%p), got %v (%p)", tc.value, tc.value, got, got)
+			}
+		})
+	}
+}
+
+func TestToList(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name  string
+		value value.Value
+		want  value.Value
+	}{
+		{"true", value.NewBool(true), list(value.NewBool(true))},
+		{"false", value.NewBool(false), value.NewList(nil)},
+		{"null", value.NewNull(), value.NewList(nil)},
+		{"zero", value.NewInt(0), list(value.NewInt(0))},
+		{"positive int", value.NewInt(123), list(value.NewInt(1), value.NewInt(2), value.NewInt(3))},
+		{"negative int", value.NewInt(-45), list(value.NewInt(-4), value.NewInt(-5))},
+		{"empty string", value.NewString(""), value.NewList(nil)},
+		{"string", value.NewString("abc"), list(value.NewString("a"), value.NewString("b"), value.NewString("c"))},
+		{"rope", value.NewConcatString(value.NewString("abcdefghijklmnopqrstuvwxyz"), value.NewString("0123456789")), chars("abcdefghijklmnopqrstuvwxyz0123456789")},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := value.ToList(tc.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !value.Equal(got, tc.want) {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+
+			if got, err := value.NewHeap().ToList(tc.value); err != nil || !value.Equal(got, tc.want) {
+				t.Errorf("want %v from a heap, got %v (%v)", tc.want, got, err)
+			}
+		})
+	}
+}
+
+func TestListBlockConversions(t *testing.T) {
+	t.Parallel()
+
+	block := value.NewBlock(value.NewInt(1))
+
+	if _, err := value.ToList(block); err == nil {
+		t.Error("want an error converting a block to a list, got nil")
+	}
+
+	// Lists can hold blocks, but then they can't be converted to strings
+	l := list(value.NewInt(1), block)
+	if _, err := value.ToString(l); err == nil {
+		t.Error("want an error converting a list holding a block to a string, got nil")
+	}
+
+	if got, err := value.ToInt(l); err != nil || got.Value != 2 {
+		t.Errorf("want 2, got %v (%v)", got, err)
+	}
+}
+
+func TestListDump(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name string
+		expr value.Value
+		want string
+	}{
+		{"empty", value.NewList(nil), "List()"},
+		{"values", list(value.NewInt(1), value.NewString("a")), "List(Number(1), String(a))"},
+		{"nested", list(list(value.NewNull())), "List(List(Null()))"},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.expr.Dump(); tc.want != got {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -22,6 +22,11 @@ func (n *Null) AsString() *String {
 	return nullString
 }
 
+// AsList converts the caller to an empty runtime List value.
+func (n *Null) AsList() *List {
+	return emptyList
+}
+
 // AsExpr returns the value itself as an Expression interface implementation.
 func (n *Null) AsExpr() Expression {
 	return n
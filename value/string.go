@@ -2,42 +2,25 @@ package value
 
 import (
 	"fmt"
-	"math"
 	"strconv"
-	"sync"
+	"strings"
+	"sync/atomic"
+	"unsafe"
 
 	"github.com/polyscone/knight/ast"
-	"github.com/polyscone/knight/options"
+	"github.com/polyscone/knight/internal/gencache"
 )
 
 // MaxInternStringLength defines the maximum length of an interned string.
 // Any strings that go over this length will not be interned.
 const MaxInternStringLength = 32
 
-var strs = struct {
-	sync.Mutex
-	data map[string]*String
-}{data: make(map[string]*String)}
-
-var concats = struct {
-	sync.Mutex
-	data map[int]*String
-}{data: make(map[int]*String)}
-
-var substrs = struct {
-	sync.Mutex
-	data map[int]*String
-}{data: make(map[int]*String)}
-
-var substrsSlow = struct {
-	sync.Mutex
-	data map[int]map[int]*String
-}{data: make(map[int]map[int]*String)}
-
-var intStrs = struct {
-	sync.Mutex
-	data map[int]*String
-}{data: make(map[int]*String)}
+// tags is the source of the unique tags that are given to interned strings so
+// they can be used as cache keys.
+// Tags are never reused, even after a string is evicted, so a cached result
+// can never be confused with the result for a different string, and they're
+// shared by every heap so strings from different heaps can be mixed safely.
+var tags int64
 
 var (
 	nullString  = NewString("null")
@@ -46,15 +29,91 @@ var (
 )
 
 // String represents a runtime string value.
+//
+// Concatenating long strings doesn't copy them straight away, instead the
+// result is a rope that refers to both halves and is only flattened into a
+// single string when its contents are needed.
+// This means building a string up piece by piece is linear rather than
+// quadratic as long as the string isn't read in between.
+//
+// Flattening publishes the result atomically rather than modifying the string,
+// so ropes are as safe to share between goroutines as any other string.
 type String struct {
-	Value string
-	tag   int
+	value  string
+	tag    int
+	length int
+
+	// rope points to the *rope for a string that was created by
+	// concatenation, and is nil otherwise.
+	// Once the string has been created it's always accessed atomically.
+	rope unsafe.Pointer
+}
+
+// rope holds the halves of a String that hasn't been flattened yet, or the
+// flattened contents once the halves have been released.
+// A rope is never modified after it has been created.
+type rope struct {
+	lhs, rhs *String
+	value    string
+}
+
+// Value returns the contents of the string, flattening it first if it's a
+// rope.
+func (s *String) Value() string {
+	r := (*rope)(atomic.LoadPointer(&s.rope))
+	switch {
+	case r == nil:
+		return s.value
+	case r.lhs == nil:
+		return r.value
+	}
+
+	return s.flatten()
+}
+
+// Len returns the length of the string in bytes without flattening it.
+func (s *String) Len() int {
+	return s.length
+}
+
+// flatten copies the contents of every leaf in the rope into a single string
+// and then replaces the rope with one that holds the result, releasing the
+// leaves.
+// The rope is walked with an explicit stack because strings built up one piece
+// at a time in a loop produce ropes that are very deep.
+//
+// If several goroutines flatten the same rope at once they each do the work,
+// but they all publish the same result.
+func (s *String) flatten() string {
+	var sb strings.Builder
+	sb.Grow(s.length)
+
+	stack := []*String{s}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		r := (*rope)(atomic.LoadPointer(&node.rope))
+		switch {
+		case r == nil:
+			sb.WriteString(node.value)
+		case r.lhs == nil:
+			sb.WriteString(r.value)
+		default:
+			stack = append(stack, r.rhs, r.lhs)
+		}
+	}
+
+	value := sb.String()
+	atomic.StorePointer(&s.rope, unsafe.Pointer(&rope{value: value}))
+
+	return value
 }
 
 // AsBool converts the caller to a false Bool runtime value if the caller's
 // value is an empty string, or to a true Bool value otherwise.
 func (s *String) AsBool() *Bool {
-	if s.Value != "" {
+	if s.length != 0 {
 		return _true
 	}
 
@@ -63,7 +122,7 @@ func (s *String) AsBool() *Bool {
 
 // AsInt converts the caller to a runtime Int value.
 func (s *String) AsInt() *Int {
-	return NewInt(Atoi(s.Value))
+	return defaultHeap.atoi(s.Value())
 }
 
 // AsString returns the caller without modification.
@@ -71,6 +130,12 @@ func (s *String) AsString() *String {
 	return s
 }
 
+// AsList converts the caller to a runtime List value that holds each of its
+// bytes as a string.
+func (s *String) AsList() *List {
+	return defaultHeap.chars(s)
+}
+
 // AsExpr returns the value itself as an Expression interface implementation.
 func (s *String) AsExpr() Expression {
 	return s
@@ -78,7 +143,7 @@ func (s *String) AsExpr() Expression {
 
 // Dump prints a string form of String for testing.
 func (s *String) Dump() string {
-	return fmt.Sprintf("String(%v)", s.Value)
+	return fmt.Sprintf("String(%v)", s.Value())
 }
 
 // String prints a string form of the String as an s-expression for testing.
@@ -89,129 +154,121 @@ func (s *String) String() string {
 
 // ASTString returns a string representation of the AST in the requested style.
 func (s *String) ASTString(style ast.Style) string {
-	return fmt.Sprintf("%q", s.Value)
+	return fmt.Sprintf("%q", s.Value())
 }
 
 // NewString will return a runtime String value that wraps the given string.
 func NewString(s string) *String {
+	return defaultHeap.NewString(s)
+}
+
+// NewString will return a runtime String value that wraps the given string,
+// interning it in the heap.
+func (h *Heap) NewString(s string) *String {
 	if len(s) > MaxInternStringLength {
 		return NewUniqueString(s)
 	}
 
-	if !options.Reckless {
-		strs.Lock()
-		defer strs.Unlock()
-	}
-
-	if v, ok := strs.data[s]; ok {
-		return v
-	}
-
-	v := String{
-		Value: s,
-		tag:   len(strs.data) + 1,
-	}
-
-	strs.data[s] = &v
-
-	return &v
+	//nolint:forcetypeassert // the cache only holds strings
+	return h.strs.LoadOrCreateString(s, func() interface{} {
+		return &String{
+			value:  s,
+			tag:    int(atomic.AddInt64(&tags, 1)),
+			length: len(s),
+		}
+	}).(*String)
 }
 
 // NewUniqueString will return a runtime String value that wraps the given
 // string, but the returned object will always be newly allocated and never interned.
 func NewUniqueString(s string) *String {
-	return &String{Value: s}
+	return &String{value: s, length: len(s)}
 }
 
 // NewSubString will return a runtime String value that wraps the given string
 // from the given start and end indices.
 func NewSubString(s *String, start, end int) *String {
-	if s.tag == 0 {
-		return NewString(s.Value[start:end])
-	}
-
-	if start <= math.MaxUint16 && end <= math.MaxUint16 {
-		if !options.Reckless {
-			substrs.Lock()
-			defer substrs.Unlock()
-		}
-
-		key := s.tag<<32 | start<<16 | end
+	return defaultHeap.NewSubString(s, start, end)
+}
 
-		if v, ok := substrs.data[key]; ok {
-			return v
+// NewSubString will return a runtime String value that wraps the given string
+// from the given start and end indices, interning it in the heap.
+//
+// Ropes are flattened by the first substring that's taken from them, so taking
+// many substrings of the same rope, like a GET for every index, only copies it
+// once.
+func (h *Heap) NewSubString(s *String, start, end int) *String {
+	if s.tag == 0 {
+		if start == 0 && end == s.length {
+			return s
 		}
 
-		v := NewString(s.Value[start:end])
-
-		substrs.data[key] = v
-
-		return v
-	}
-
-	if !options.Reckless {
-		substrsSlow.Lock()
-		defer substrsSlow.Unlock()
-	}
-
-	key1 := s.tag
-	key2 := start<<32 | end
-
-	if _, ok := substrsSlow.data[key1]; !ok {
-		substrsSlow.data[key1] = make(map[int]*String)
-	}
-
-	if v, ok := substrsSlow.data[key1][key2]; ok {
-		return v
+		return h.NewString(s.Value()[start:end])
 	}
 
-	v := NewString(s.Value[start:end])
-
-	substrsSlow.data[key1][key2] = v
+	key := gencache.Key{Ints: [3]int{s.tag, start, end}}
 
-	return v
+	//nolint:forcetypeassert // the cache only holds strings
+	return h.substrs.LoadOrCreate(key, func() interface{} {
+		return h.NewString(s.value[start:end])
+	}).(*String)
 }
 
 // NewConcatString will return a runtime String value that is the concatenation
 // of both given runtime String values.
 func NewConcatString(lhs, rhs *String) *String {
-	if lhs.tag == 0 || rhs.tag == 0 {
-		return NewString(lhs.Value + rhs.Value)
-	}
-
-	if !options.Reckless {
-		concats.Lock()
-		defer concats.Unlock()
-	}
-
-	key := lhs.tag<<32 | rhs.tag
+	return defaultHeap.NewConcatString(lhs, rhs)
+}
 
-	if v, ok := concats.data[key]; ok {
-		return v
+// NewConcatString will return a runtime String value that is the concatenation
+// of both given runtime String values, interning it in the heap.
+//
+// Short results are flattened and interned straight away, but anything longer
+// than MaxInternStringLength is returned as a rope.
+func (h *Heap) NewConcatString(lhs, rhs *String) *String {
+	switch {
+	case lhs.length == 0:
+		return rhs
+	case rhs.length == 0:
+		return lhs
+	case lhs.length+rhs.length > MaxInternStringLength:
+		return &String{
+			length: lhs.length + rhs.length,
+			rope:   unsafe.Pointer(&rope{lhs: lhs, rhs: rhs}),
+		}
+	case lhs.tag == 0 || rhs.tag == 0:
+		return h.NewString(lhs.Value() + rhs.Value())
 	}
 
-	v := NewString(lhs.Value + rhs.Value)
-
-	concats.data[key] = v
+	key := gencache.Key{Ints: [3]int{lhs.tag, rhs.tag}}
 
-	return v
+	//nolint:forcetypeassert // the cache only holds strings
+	return h.concats.LoadOrCreate(key, func() interface{} {
+		return h.NewString(lhs.value + rhs.value)
+	}).(*String)
 }
 
 // NewIntString will return a runtime String value that is the string
 // representation of the given integer.
 func NewIntString(i int) *String {
-	if !options.Reckless {
-		intStrs.Lock()
-		defer intStrs.Unlock()
-	}
+	return defaultHeap.NewIntString(i)
+}
 
-	if v, ok := intStrs.data[i]; ok {
-		return v
+// intString returns the string representation of the given Int, interning it
+// in the heap if it fits in an int.
+func (h *Heap) intString(i *Int) *String {
+	if i.big != nil {
+		return h.NewString(i.big.String())
 	}
 
-	v := NewString(strconv.Itoa(i))
-
-	intStrs.data[i] = v
+	return h.NewIntString(i.Value)
+}
 
-	return v
+// NewIntString will return a runtime String value that is the string
+// representation of the given integer, interning it in the heap.
+func (h *Heap) NewIntString(i int) *String {
+	//nolint:forcetypeassert // the cache only holds strings
+	return h.intStrs.LoadOrCreate(gencache.Key{Ints: [3]int{i}}, func() interface{} {
+		return h.NewString(strconv.Itoa(i))
+	}).(*String)
 }
@@ -1,8 +1,11 @@
 package value_test
 
 import (
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/polyscone/knight/options"
 	"github.com/polyscone/knight/value"
 )
 
@@ -66,3 +69,118 @@ func TestStringDump(t *testing.T) {
 		})
 	}
 }
+
+func TestStringRope(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("abcdefghij", 5)
+
+	tt := []struct {
+		name  string
+		build func() *value.String
+		want  string
+	}{
+		{"short concat", func() *value.String {
+			return value.NewConcatString(value.NewString("foo"), value.NewString("bar"))
+		}, "foobar"},
+		{"long concat", func() *value.String {
+			return value.NewConcatString(value.NewString(long), value.NewString("!"))
+		}, long + "!"},
+		{"empty lhs", func() *value.String {
+			return value.NewConcatString(value.NewString(""), value.NewString(long))
+		}, long},
+		{"deep rope", func() *value.String {
+			s := value.NewString("")
+			for n := 0; n < 100000; n++ {
+				s = value.NewConcatString(s, value.NewString("x"))
+			}
+
+			return s
+		}, strings.Repeat("x", 100000)},
+		{"substring of lhs", func() *value.String {
+			return value.NewSubString(value.NewConcatString(value.NewString(long), value.NewString(long)), 1, 3)
+		}, "bc"},
+		{"substring of rhs", func() *value.String {
+			return value.NewSubString(value.NewConcatString(value.NewString("s"), value.NewString(long)), 1, len(long)+1)
+		}, long},
+		{"substring across halves", func() *value.String {
+			return value.NewSubString(value.NewConcatString(value.NewString(long), value.NewString(long)), 48, 52)
+		}, "ijab"},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := tc.build()
+
+			if got := s.Len(); got != len(tc.want) {
+				t.Errorf("want length %v, got %v", len(tc.want), got)
+			}
+
+			if !value.Equal(s, value.NewString(tc.want)) {
+				t.Errorf("want %q to equal %q", s.Value(), tc.want)
+			}
+
+			if got := s.Value(); got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+
+			// Flattening must not change the result
+			if got := s.Value(); got != tc.want {
+				t.Errorf("want %q after flattening, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestStringRopeConcurrent(t *testing.T) {
+	t.Parallel()
+
+	if options.Reckless {
+		t.Skip("reckless builds don't synchronise heaps")
+	}
+
+	long := strings.Repeat("abcdefghij", 5)
+	want := long + long + long
+
+	// The inner rope is shared by the outer one, so flattening either of them
+	// reads the other while it might be being flattened
+	inner := value.NewConcatString(value.NewString(long), value.NewString(long))
+	outer := value.NewConcatString(inner, value.NewString(long))
+
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+
+		go func(n int) {
+			defer wg.Done()
+
+			s, want := outer, want
+			if n%2 == 0 {
+				s, want = inner, long+long
+			}
+
+			if got := s.Value(); got != want {
+				t.Errorf("want %q, got %q", want, got)
+			}
+
+			if got := value.NewSubString(s, 48, 52).Value(); got != "ijab" {
+				t.Errorf("want %q, got %q", "ijab", got)
+			}
+		}(n)
+	}
+	wg.Wait()
+}
+
+func BenchmarkConcatString(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		s := value.NewString("")
+		for i := 0; i < 10000; i++ {
+			s = value.NewConcatString(s, value.NewString("x"))
+		}
+
+		_ = s.Value()
+	}
+}
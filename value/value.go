@@ -21,10 +21,72 @@ type Value interface {
 	AsBool() *Bool
 	AsInt() *Int
 	AsString() *String
+	AsList() *List
 	AsExpr() Expression
 	Dump() string
 }
 
+// ConversionError is returned by the checked conversion functions when a value
+// has no representation in the requested type, which is undefined behaviour in
+// the Knight spec.
+type ConversionError struct {
+	Value Value
+	To    string
+}
+
+// Error returns a description of the failed conversion.
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("cannot convert %v to %s", e.Value, e.To)
+}
+
+// ToBool converts the given value to a runtime Bool value.
+// Unlike the AsBool method it returns an error instead of panicking when the
+// value cannot be converted.
+func ToBool(v Value) (*Bool, error) {
+	if _, ok := v.(*Block); ok {
+		return nil, &ConversionError{Value: v, To: "bool"}
+	}
+
+	return v.AsBool(), nil
+}
+
+// ToInt converts the given value to a runtime Int value.
+// Unlike the AsInt method it returns an error instead of panicking when the
+// value cannot be converted.
+func ToInt(v Value) (*Int, error) {
+	if _, ok := v.(*Block); ok {
+		return nil, &ConversionError{Value: v, To: "int"}
+	}
+
+	return v.AsInt(), nil
+}
+
+// ToString converts the given value to a runtime String value.
+// Unlike the AsString method it returns an error instead of panicking when the
+// value cannot be converted.
+func ToString(v Value) (*String, error) {
+	if _, ok := v.(*Block); ok {
+		return nil, &ConversionError{Value: v, To: "string"}
+	}
+
+	if l, ok := v.(*List); ok {
+		return l.Join("\n")
+	}
+
+	return v.AsString(), nil
+}
+
+// ToList converts the given value to a runtime List value.
+// Unlike the AsList method it returns an error instead of panicking when the
+// value cannot be converted.
+func ToList(v Value) (*List, error) {
+	if _, ok := v.(*Block); ok {
+		return nil, &ConversionError{Value: v, To: "list"}
+	}
+
+	return v.AsList(), nil
+}
+
 // Equal checks to see if two value are equal to each other.
 //
 // Equality is determined by both the type and the value.
@@ -34,11 +96,24 @@ func Equal(lhs, rhs Value) bool {
 	case *Int:
 		rhs, ok := rhs.(*Int)
 
-		return ok && lhs.Value == rhs.Value
+		return ok && lhs.Cmp(rhs) == 0
 	case *String:
 		rhs, ok := rhs.(*String)
 
-		return ok && (lhs == rhs || lhs.Value == rhs.Value)
+		return ok && (lhs == rhs || lhs.Len() == rhs.Len() && lhs.Value() == rhs.Value())
+	case *List:
+		rhs, ok := rhs.(*List)
+		if !ok || lhs.Len() != rhs.Len() {
+			return false
+		}
+
+		for n, v := range lhs.Value {
+			if !Equal(v, rhs.Value[n]) {
+				return false
+			}
+		}
+
+		return true
 	}
 
 	return lhs == rhs
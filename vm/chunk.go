@@ -0,0 +1,89 @@
+package vm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/polyscone/knight/ast"
+	"github.com/polyscone/knight/value"
+)
+
+// Chunk is a compiled expression.
+// Every program, block body, and program run by EVAL is compiled to its
+// own chunk.
+type Chunk struct {
+	// Code holds the bytecode, where each instruction is a one byte opcode
+	// followed by its operands, which are little-endian 4-byte integers.
+	Code []byte
+
+	// Consts holds the literal values used by OpConst.
+	Consts []value.Value
+
+	// Exprs holds the expressions wrapped by OpBlock.
+	Exprs []ast.Node
+
+	// Messages holds the error messages used by OpFail.
+	Messages []string
+
+	spans []spanEntry
+}
+
+// spanEntry records that every instruction from pc onwards, up to the next
+// entry, was compiled from the node with the given span.
+type spanEntry struct {
+	pc   int
+	span ast.Span
+}
+
+// Span returns the span of the node that the instruction at the given
+// position was compiled from.
+func (c *Chunk) Span(pc int) ast.Span {
+	n := sort.Search(len(c.spans), func(n int) bool { return c.spans[n].pc > pc })
+	if n == 0 {
+		return ast.Span{}
+	}
+
+	return c.spans[n-1].span
+}
+
+// Disassemble returns a human readable listing of the chunk's bytecode.
+// Variables are named using the given store, which should be the one the
+// chunk's program was parsed with.
+func (c *Chunk) Disassemble(globals *value.GlobalStore) string {
+	var sb strings.Builder
+
+	for pc := 0; pc < len(c.Code); {
+		op := Op(c.Code[pc])
+
+		fmt.Fprintf(&sb, "%04d %v", pc, op)
+
+		if op.operands() > 0 {
+			arg := operand(c.Code, pc+1)
+
+			switch op {
+			case OpConst:
+				fmt.Fprintf(&sb, " %v", c.Consts[arg])
+			case OpBlock:
+				fmt.Fprintf(&sb, " %v", c.Exprs[arg])
+			case OpGet, OpAssign:
+				fmt.Fprintf(&sb, " %v", globals.Slot(arg).Name)
+			case OpFail:
+				fmt.Fprintf(&sb, " %q", c.Messages[arg])
+			default:
+				fmt.Fprintf(&sb, " %04d", arg)
+			}
+		}
+
+		sb.WriteByte('\n')
+
+		pc += 1 + 4*op.operands()
+	}
+
+	return sb.String()
+}
+
+func operand(code []byte, pc int) int {
+	return int(binary.LittleEndian.Uint32(code[pc:]))
+}
@@ -0,0 +1,313 @@
+package vm
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/polyscone/knight/ast"
+	"github.com/polyscone/knight/token"
+	"github.com/polyscone/knight/value"
+)
+
+type compiler struct {
+	chunk  *Chunk
+	span   ast.Span
+	consts map[value.Value]int
+	exprs  map[ast.Node]int
+}
+
+// Compile compiles the given expression into a chunk of bytecode.
+// Blocks are not compiled until they're called, so the chunk only contains
+// code for the expression itself.
+func Compile(root ast.Node) (*Chunk, error) {
+	c := compiler{
+		chunk:  &Chunk{},
+		consts: make(map[value.Value]int),
+		exprs:  make(map[ast.Node]int),
+	}
+
+	if err := c.compile(root); err != nil {
+		return nil, err
+	}
+
+	c.emit(OpReturn)
+
+	return c.chunk, nil
+}
+
+func (c *compiler) compile(node ast.Node) error {
+	// Literal leaves don't have a span, so any instructions compiled for them
+	// use the span of the closest node that does, which matches how the
+	// tree-walk interpreter reports errors
+	if span, ok := ast.Locate(node); ok && !span.IsZero() {
+		outer := c.span
+		c.span = span
+
+		defer func() { c.span = outer }()
+	}
+
+	switch v := node.(type) {
+	case *value.Bool, *value.Int, *value.String, *value.Null, *value.List:
+		//nolint:forcetypeassert // every case is a value
+		c.emitArg(OpConst, c.constant(v.(value.Value)))
+	case *value.Variable:
+		c.emitArg(OpGet, v.Global.Slot)
+	case *ast.ASCII:
+		return c.compileArgs(OpASCII, v.Arg)
+	case *ast.Block:
+		c.emitArg(OpBlock, c.expr(v.Body))
+	case *ast.CallBlock:
+		return c.compileArgs(OpCall, v.Block)
+	case *ast.Dump:
+		return c.compileArgs(OpDump, v.Arg)
+	case *ast.Eval:
+		return c.compileArgs(OpEval, v.Arg)
+	case *ast.Get:
+		return c.compileArgs(OpGetStr, v.Str, v.Start, v.Count)
+	case *ast.If:
+		return c.compileIf(v)
+	case *ast.Length:
+		return c.compileArgs(OpLength, v.Arg)
+	case *ast.Output:
+		return c.compileArgs(OpOutput, v.Arg)
+	case *ast.Prompt:
+		c.emit(OpPrompt)
+	case *ast.Quit:
+		return c.compileArgs(OpQuit, v.Arg)
+	case *ast.Random:
+		c.emit(OpRandom)
+	case *ast.Substitute:
+		return c.compileArgs(OpSetStr, v.Str, v.Start, v.Count, v.Replacement)
+	case *ast.While:
+		return c.compileWhile(v)
+	case *ast.Call:
+		c.emitArg(OpFail, c.message(fmt.Sprintf("undefined function: %v", v.Name)))
+	case *ast.Unary:
+		if err := c.compile(v.Node); err != nil {
+			return err
+		}
+
+		switch v.Op {
+		case token.Not:
+			c.emit(OpNot)
+		case token.Noop:
+			// Nothing to do
+		case token.System:
+			c.emit(OpSystem)
+		case token.Box:
+			c.emit(OpBox)
+		case token.Head:
+			c.emit(OpHead)
+		case token.Tail:
+			c.emit(OpTail)
+		default:
+			return fmt.Errorf("unknown unary operator: %s", v)
+		}
+	case *ast.Binary:
+		return c.compileBinary(v)
+	default:
+		return fmt.Errorf("unknown node: %s", node)
+	}
+
+	return nil
+}
+
+func (c *compiler) compileIf(v *ast.If) error {
+	if err := c.compile(v.Condition); err != nil {
+		return err
+	}
+
+	alternative := c.emitJump(OpJumpIfFalse)
+
+	if err := c.compile(v.Consequence); err != nil {
+		return err
+	}
+
+	end := c.emitJump(OpJump)
+
+	c.patch(alternative)
+
+	if err := c.compile(v.Alternative); err != nil {
+		return err
+	}
+
+	c.patch(end)
+
+	return nil
+}
+
+func (c *compiler) compileWhile(v *ast.While) error {
+	start := len(c.chunk.Code)
+
+	if err := c.compile(v.Condition); err != nil {
+		return err
+	}
+
+	end := c.emitJump(OpJumpIfFalse)
+
+	if err := c.compile(v.Body); err != nil {
+		return err
+	}
+
+	c.emit(OpPop)
+	c.emitArg(OpLoop, start)
+	c.patch(end)
+	c.emitArg(OpConst, c.constant(value.NewNull()))
+
+	return nil
+}
+
+// compileArgs compiles each of the given arguments in order, and then emits
+// the given opcode to consume them.
+func (c *compiler) compileArgs(op Op, args ...ast.Node) error {
+	for _, arg := range args {
+		if err := c.compile(arg); err != nil {
+			return err
+		}
+	}
+
+	c.emit(op)
+
+	return nil
+}
+
+func (c *compiler) compileBinary(v *ast.Binary) error {
+	switch v.Op {
+	case token.Assign:
+		if err := c.compile(v.RHS); err != nil {
+			return err
+		}
+
+		variable, ok := v.LHS.(*value.Variable)
+		if !ok {
+			c.emitArg(OpFail, c.message(fmt.Sprintf("cannot assign to %s", v.LHS)))
+
+			return nil
+		}
+
+		c.emitArg(OpAssign, variable.Global.Slot)
+
+		return nil
+	case token.And, token.Or:
+		if err := c.compile(v.LHS); err != nil {
+			return err
+		}
+
+		var end int
+		if v.Op == token.And {
+			end = c.emitJump(OpJumpIfFalseKeep)
+		} else {
+			end = c.emitJump(OpJumpIfTrueKeep)
+		}
+
+		c.emit(OpPop)
+
+		if err := c.compile(v.RHS); err != nil {
+			return err
+		}
+
+		c.patch(end)
+
+		return nil
+	case token.Chain:
+		if err := c.compile(v.LHS); err != nil {
+			return err
+		}
+
+		c.emit(OpPop)
+
+		return c.compile(v.RHS)
+	}
+
+	ops := map[token.Kind]Op{
+		token.Add:     OpAdd,
+		token.Sub:     OpSub,
+		token.Mul:     OpMul,
+		token.Div:     OpDiv,
+		token.Mod:     OpMod,
+		token.Exp:     OpExp,
+		token.Less:    OpLess,
+		token.Greater: OpGreater,
+		token.Equal:   OpEqual,
+	}
+
+	op, ok := ops[v.Op]
+	if !ok {
+		return fmt.Errorf("unknown binary operator: %s", v)
+	}
+
+	if err := c.compile(v.LHS); err != nil {
+		return err
+	}
+
+	if err := c.compile(v.RHS); err != nil {
+		return err
+	}
+
+	c.emit(op)
+
+	return nil
+}
+
+func (c *compiler) emit(op Op) {
+	chunk := c.chunk
+
+	if n := len(chunk.spans); n == 0 || chunk.spans[n-1].span != c.span {
+		chunk.spans = append(chunk.spans, spanEntry{pc: len(chunk.Code), span: c.span})
+	}
+
+	chunk.Code = append(chunk.Code, byte(op))
+}
+
+func (c *compiler) emitArg(op Op, arg int) {
+	c.emit(op)
+
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(arg))
+
+	c.chunk.Code = append(c.chunk.Code, b[:]...)
+}
+
+// emitJump emits a jump instruction with a placeholder target and returns the
+// position of the placeholder so it can be patched once the target is known.
+func (c *compiler) emitJump(op Op) int {
+	c.emitArg(op, 0)
+
+	return len(c.chunk.Code) - 4
+}
+
+// patch sets the target of the jump at the given position to the position of
+// the next instruction to be emitted.
+func (c *compiler) patch(pos int) {
+	binary.LittleEndian.PutUint32(c.chunk.Code[pos:], uint32(len(c.chunk.Code)))
+}
+
+func (c *compiler) constant(v value.Value) int {
+	if n, ok := c.consts[v]; ok {
+		return n
+	}
+
+	n := len(c.chunk.Consts)
+	c.chunk.Consts = append(c.chunk.Consts, v)
+	c.consts[v] = n
+
+	return n
+}
+
+func (c *compiler) expr(node ast.Node) int {
+	if n, ok := c.exprs[node]; ok {
+		return n
+	}
+
+	n := len(c.chunk.Exprs)
+	c.chunk.Exprs = append(c.chunk.Exprs, node)
+	c.exprs[node] = n
+
+	return n
+}
+
+func (c *compiler) message(msg string) int {
+	c.chunk.Messages = append(c.chunk.Messages, msg)
+
+	return len(c.chunk.Messages) - 1
+}
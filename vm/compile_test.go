@@ -0,0 +1,92 @@
+package vm_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/polyscone/knight/lexer"
+	"github.com/polyscone/knight/parser"
+	"github.com/polyscone/knight/value"
+	"github.com/polyscone/knight/vm"
+)
+
+func TestCompile(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			"if",
+			"IF T 1 2",
+			"0000 const true\n" +
+				"0005 jump_if_false 0020\n" +
+				"0010 const 1\n" +
+				"0015 jump 0025\n" +
+				"0020 const 2\n" +
+				"0025 return\n",
+		},
+		{
+			"while",
+			"WHILE < i 3 = i + i 1",
+			"0000 get i\n" +
+				"0005 const 3\n" +
+				"0010 less\n" +
+				"0011 jump_if_false 0038\n" +
+				"0016 get i\n" +
+				"0021 const 1\n" +
+				"0026 add\n" +
+				"0027 assign i\n" +
+				"0032 pop\n" +
+				"0033 loop 0000\n" +
+				"0038 const null\n" +
+				"0043 return\n",
+		},
+		{
+			"and/or",
+			"& a | b CALL c",
+			"0000 get a\n" +
+				"0005 jump_if_false_keep 0028\n" +
+				"0010 pop\n" +
+				"0011 get b\n" +
+				"0016 jump_if_true_keep 0028\n" +
+				"0021 pop\n" +
+				"0022 get c\n" +
+				"0027 call\n" +
+				"0028 return\n",
+		},
+		{
+			"assign to non-variable",
+			"= 1 2",
+			"0000 const 2\n" +
+				"0005 fail \"cannot assign to 1\"\n" +
+				"0010 return\n",
+		},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := lexer.New()
+			p := parser.New(l)
+			g := value.NewGlobalStore()
+			program, err := p.Parse(g, strings.NewReader(tc.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			chunk, err := vm.Compile(program.Root)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := chunk.Disassemble(g); got != tc.want {
+				t.Errorf("want:\n%v\ngot:\n%v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package vm
+
+import "strconv"
+
+// Opcodes.
+// Unless otherwise noted an opcode has no operands, pops its arguments off the
+// value stack, and pushes its result back on to it.
+const (
+	// OpConst pushes the literal value at the index given by its operand.
+	OpConst Op = iota
+	// OpBlock pushes a new block that wraps the expression at the index given
+	// by its operand.
+	OpBlock
+	// OpGet pushes the value of the variable in the global slot given by its
+	// operand, calling it first if it holds a block.
+	OpGet
+	// OpAssign assigns the value on the top of the stack to the variable in
+	// the global slot given by its operand without popping it.
+	OpAssign
+	// OpFail raises an error using the message at the index given by its
+	// operand.
+	OpFail
+	// OpPop discards the value on the top of the stack.
+	OpPop
+	// OpJump moves execution to the absolute position given by its operand.
+	OpJump
+	// OpJumpIfFalse pops the top of the stack and moves execution to the
+	// position given by its operand if the value is falsey.
+	OpJumpIfFalse
+	// OpJumpIfFalseKeep moves execution to the position given by its operand,
+	// leaving the top of the stack in place, if the value is falsey.
+	OpJumpIfFalseKeep
+	// OpJumpIfTrueKeep moves execution to the position given by its operand,
+	// leaving the top of the stack in place, if the value is truthy.
+	OpJumpIfTrueKeep
+	// OpLoop works like OpJump, but also checks for cancellation.
+	OpLoop
+	// OpReturn returns from the current chunk with the top of the stack as
+	// its result.
+	OpReturn
+
+	OpCall
+	OpEval
+	OpASCII
+	OpDump
+	OpLength
+	OpOutput
+	OpPrompt
+	OpQuit
+	OpRandom
+	OpGetStr
+	OpSetStr
+	OpNot
+	OpSystem
+	OpBox
+	OpHead
+	OpTail
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpExp
+	OpLess
+	OpGreater
+	OpEqual
+)
+
+// Op is a single bytecode instruction.
+type Op byte
+
+// operands returns the number of 4-byte operands that follow the opcode.
+func (op Op) operands() int {
+	switch op {
+	case OpConst,
+		OpBlock,
+		OpGet,
+		OpAssign,
+		OpFail,
+		OpJump,
+		OpJumpIfFalse,
+		OpJumpIfFalseKeep,
+		OpJumpIfTrueKeep,
+		OpLoop:
+
+		return 1
+	}
+
+	return 0
+}
+
+// String returns a string representation of the opcode.
+func (op Op) String() string {
+	switch op {
+	case OpConst:
+		return "const"
+	case OpBlock:
+		return "block"
+	case OpGet:
+		return "get"
+	case OpAssign:
+		return "assign"
+	case OpFail:
+		return "fail"
+	case OpPop:
+		return "pop"
+	case OpJump:
+		return "jump"
+	case OpJumpIfFalse:
+		return "jump_if_false"
+	case OpJumpIfFalseKeep:
+		return "jump_if_false_keep"
+	case OpJumpIfTrueKeep:
+		return "jump_if_true_keep"
+	case OpLoop:
+		return "loop"
+	case OpReturn:
+		return "return"
+	case OpCall:
+		return "call"
+	case OpEval:
+		return "eval"
+	case OpASCII:
+		return "ascii"
+	case OpDump:
+		return "dump"
+	case OpLength:
+		return "length"
+	case OpOutput:
+		return "output"
+	case OpPrompt:
+		return "prompt"
+	case OpQuit:
+		return "quit"
+	case OpRandom:
+		return "random"
+	case OpGetStr:
+		return "get_str"
+	case OpSetStr:
+		return "set_str"
+	case OpNot:
+		return "not"
+	case OpSystem:
+		return "system"
+	case OpBox:
+		return "box"
+	case OpHead:
+		return "head"
+	case OpTail:
+		return "tail"
+	case OpAdd:
+		return "add"
+	case OpSub:
+		return "sub"
+	case OpMul:
+		return "mul"
+	case OpDiv:
+		return "div"
+	case OpMod:
+		return "mod"
+	case OpExp:
+		return "exp"
+	case OpLess:
+		return "less"
+	case OpGreater:
+		return "greater"
+	case OpEqual:
+		return "equal"
+	}
+
+	return strconv.Itoa(int(op))
+}
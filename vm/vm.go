@@ -0,0 +1,437 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/polyscone/knight/ast"
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/value"
+)
+
+// VM is an interpreter.Backend that compiles expressions to bytecode and
+// executes them on a stack machine.
+//
+// Each program is compiled to a unit that holds a chunk for the program and a
+// chunk for every block body within it.
+// Units are cached in the interpreter's runtime rather than in the VM, so
+// they're bounded and discarded in the same way as the programs parsed by EVAL,
+// and a VM can be shared by any number of interpreters.
+//
+// Compiled code refers to variables by their slot in the interpreter's global
+// store, so programs must be parsed with the same store that the interpreter
+// was created with.
+type VM struct{}
+
+// unit holds the compiled chunks for a program and every block body within it,
+// which are compiled together so that they can be cached and discarded
+// together.
+// A unit is never modified after it has been compiled, so it can be shared by
+// interpreters that share a runtime.
+type unit struct {
+	root   *Chunk
+	chunks map[ast.Node]*Chunk
+}
+
+// frame is an active chunk.
+type frame struct {
+	chunk *Chunk
+	pc    int
+
+	// entered records whether the frame pushed a frame onto the Knight call
+	// stack, which must be popped again when the frame returns.
+	entered bool
+
+	// block records whether the frame is running the body of a called block,
+	// which means it can be replaced by a call that it makes in tail position.
+	block bool
+
+	// unit is the unit that the frame's chunk belongs to.
+	unit *unit
+}
+
+// Run executes the given expression using the given interpreter's runtime
+// state and builtin functions.
+//
+// Steps are counted against the interpreter's budget for every instruction
+// that does work, so a step budget will run out at roughly, but not exactly,
+// the same point that it would for the tree-walk evaluator.
+func (vm *VM) Run(i *interpreter.Interpreter, root ast.Node) (value.Value, error) {
+	u, err := vm.load(i, root)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := []frame{{chunk: u.root, unit: u}}
+	var stack []value.Value
+
+	push := func(v value.Value) {
+		stack = append(stack, v)
+	}
+
+	pop := func() value.Value {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		return v
+	}
+
+	// fail locates the error at the current instruction and then unwinds the
+	// Knight call stack, since the frames above the program won't return
+	fail := func(f *frame, pc int, err error) (value.Value, error) {
+		err = i.Locate(f.chunk.Span(pc), err)
+
+		for n := len(frames) - 1; n >= 0; n-- {
+			if frames[n].entered {
+				i.Leave()
+			}
+		}
+
+		return nil, err
+	}
+
+	for {
+		f := &frames[len(frames)-1]
+		code := f.chunk.Code
+		pc := f.pc
+		op := Op(code[pc])
+
+		var arg int
+		if op.operands() > 0 {
+			arg = operand(code, pc+1)
+		}
+
+		f.pc += 1 + 4*op.operands()
+
+		switch op {
+		case OpJump, OpLoop, OpReturn, OpPop:
+			// Control flow isn't counted as a step
+		default:
+			if err := i.Step(); err != nil {
+				return fail(f, pc, err)
+			}
+		}
+
+		var result value.Value
+		var err error
+
+		switch op {
+		case OpConst:
+			result, err = i.Literal(f.chunk.Consts[arg])
+		case OpBlock:
+			result, err = i.Block(f.chunk.Exprs[arg])
+		case OpGet:
+			g := i.Global(arg)
+			if g.Value == nil {
+				return fail(f, pc, fmt.Errorf("attempted to access undefined variable %q", g.Name))
+			}
+
+			// Strict mode follows the spec, where a variable's block is
+			// only run by CALL
+			b, ok := g.Value.(*value.Block)
+			if !ok || i.Strict() {
+				push(g.Value)
+
+				continue
+			}
+
+			if err := vm.enter(i, &frames, &f, g.Name, pc); err != nil {
+				return fail(f, pc, err)
+			}
+
+			if err := vm.call(i, &frames, &stack, b); err != nil {
+				return fail(f, pc, err)
+			}
+
+			continue
+		case OpAssign:
+			result, err = i.Assign(i.Global(arg), pop())
+		case OpFail:
+			return fail(f, pc, errors.New(f.chunk.Messages[arg]))
+		case OpPop:
+			pop()
+
+			continue
+		case OpJump:
+			f.pc = arg
+
+			continue
+		case OpJumpIfFalse, OpJumpIfFalseKeep, OpJumpIfTrueKeep:
+			v := stack[len(stack)-1]
+			if op == OpJumpIfFalse {
+				pop()
+			}
+
+			b, err := value.ToBool(v)
+			if err != nil {
+				return fail(f, pc, err)
+			}
+
+			if b.Value == (op == OpJumpIfTrueKeep) {
+				f.pc = arg
+			}
+
+			continue
+		case OpLoop:
+			if err := i.Cancelled(); err != nil {
+				return fail(f, pc, err)
+			}
+
+			f.pc = arg
+
+			continue
+		case OpReturn:
+			if f.entered {
+				i.Leave()
+			}
+
+			frames = frames[:len(frames)-1]
+			if len(frames) == 0 {
+				return pop(), nil
+			}
+
+			continue
+		case OpCall:
+			v := pop()
+
+			b, ok := v.(*value.Block)
+			if !ok {
+				result, err = i.Call(v)
+
+				break
+			}
+
+			if err := vm.enter(i, &frames, &f, "<block>", pc); err != nil {
+				return fail(f, pc, err)
+			}
+
+			if err := vm.call(i, &frames, &stack, b); err != nil {
+				return fail(f, pc, err)
+			}
+
+			continue
+		case OpEval:
+			v := pop()
+
+			if err := i.Enter("<eval>", f.chunk.Span(pc)); err != nil {
+				return fail(f, pc, err)
+			}
+
+			compiled, err := i.CompileEval(v, func(program ast.Program) (interface{}, error) {
+				return compileUnit(program.Root)
+			})
+			if err != nil {
+				i.Leave()
+
+				return fail(f, pc, err)
+			}
+
+			//nolint:forcetypeassert // the VM is the only backend that compiles
+			u := compiled.(*unit)
+			frames = append(frames, frame{chunk: u.root, entered: true, unit: u})
+
+			continue
+		case OpASCII:
+			result, err = i.ASCII(pop())
+		case OpDump:
+			result, err = i.Dump(i.Stdout(), pop())
+		case OpLength:
+			result, err = i.Length(pop())
+		case OpOutput:
+			result, err = i.Output(i.Stdout(), pop())
+		case OpPrompt:
+			// Make sure any prompt text has actually been written before
+			// blocking on input
+			if err := i.Flush(); err != nil {
+				return fail(f, pc, err)
+			}
+
+			result, err = i.Prompt(i.Stdin())
+		case OpQuit:
+			result, err = i.Quit(pop())
+		case OpRandom:
+			result, err = i.Random()
+		case OpGetStr:
+			count, start, str := pop(), pop(), pop()
+			result, err = i.Get(str, start, count)
+		case OpSetStr:
+			replacement, count, start, str := pop(), pop(), pop(), pop()
+			result, err = i.Substitute(str, start, count, replacement)
+		case OpNot:
+			result, err = i.Not(pop())
+		case OpSystem:
+			result, err = i.System(pop())
+		case OpBox:
+			result, err = i.Box(pop())
+		case OpHead:
+			result, err = i.Head(pop())
+		case OpTail:
+			result, err = i.Tail(pop())
+		default:
+			rhs, lhs := pop(), pop()
+
+			switch op {
+			case OpAdd:
+				result, err = i.Add(lhs, rhs)
+			case OpSub:
+				result, err = i.Sub(lhs, rhs)
+			case OpMul:
+				result, err = i.Mul(lhs, rhs)
+			case OpDiv:
+				result, err = i.Div(lhs, rhs)
+			case OpMod:
+				result, err = i.Mod(lhs, rhs)
+			case OpExp:
+				result, err = i.Exp(lhs, rhs)
+			case OpLess:
+				result, err = i.Less(lhs, rhs)
+			case OpGreater:
+				result, err = i.Greater(lhs, rhs)
+			case OpEqual:
+				result, err = i.Equal(lhs, rhs)
+			default:
+				err = fmt.Errorf("unknown opcode: %v", op)
+			}
+		}
+		if err != nil {
+			return fail(f, pc, err)
+		}
+
+		push(result)
+	}
+}
+
+// call starts executing the given block's expression in a new frame, which
+// will leave the Knight call stack frame that was entered for it when it
+// returns.
+// Blocks that wrap a literal don't need any code to be run, so their value is
+// pushed straight onto the stack and the Knight call stack frame is left
+// immediately.
+func (vm *VM) call(i *interpreter.Interpreter, frames *[]frame, stack *[]value.Value, b *value.Block) error {
+	switch v := b.Value.(type) {
+	case *value.Bool, *value.Int, *value.String, *value.Null, *value.List:
+		//nolint:forcetypeassert // every case is a value
+		result, err := i.Literal(v.(value.Value))
+
+		i.Leave()
+
+		if err != nil {
+			return err
+		}
+
+		*stack = append(*stack, result)
+
+		return nil
+	}
+
+	// Most blocks are called by the program that they were created in, but
+	// any other block's body is compiled into a unit of its own
+	u := (*frames)[len(*frames)-1].unit
+	if _, ok := u.chunks[b.Value]; !ok {
+		var err error
+		if u, err = vm.load(i, b.Value); err != nil {
+			i.Leave()
+
+			return err
+		}
+	}
+
+	*frames = append(*frames, frame{chunk: u.chunks[b.Value], entered: true, block: true, unit: u})
+
+	return nil
+}
+
+// enter pushes a frame onto the Knight call stack for a call made by the
+// instruction at the given position in the given frame.
+//
+// If the frame is running a block and only calls the result or returns after
+// the call then the call is in tail position, so the frame's Knight call stack
+// frame is popped first.
+// When the frame has nothing left to do but return it's also returned from
+// early, and it's swapped for a copy so that errors can still be located
+// within it.
+func (vm *VM) enter(i *interpreter.Interpreter, frames *[]frame, f **frame, name string, pc int) error {
+	caller := *f
+	span := caller.chunk.Span(pc)
+	if !caller.block {
+		return i.Enter(name, span)
+	}
+
+	code := caller.chunk.Code
+	next := caller.pc
+	calls := false
+	for op := Op(code[next]); op == OpJump || op == OpCall; op = Op(code[next]) {
+		if op == OpJump {
+			next = operand(code, next+1)
+		} else {
+			next++
+			calls = true
+		}
+	}
+
+	if Op(code[next]) != OpReturn {
+		return i.Enter(name, span)
+	}
+
+	i.Leave()
+
+	if calls {
+		// The frame still has to call the result, but it no longer has its
+		// own Knight call stack frame
+		caller.entered = false
+		caller.block = false
+	} else {
+		returned := *caller
+		*f = &returned
+		*frames = (*frames)[:len(*frames)-1]
+	}
+
+	return i.Enter(name, span)
+}
+
+// load returns the unit for the given program or block body, which is
+// compiled the first time it's needed and then cached in the interpreter's
+// runtime.
+func (vm *VM) load(i *interpreter.Interpreter, root ast.Node) (*unit, error) {
+	compiled, err := i.Compile(root, func(root ast.Node) (interface{}, error) {
+		return compileUnit(root)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	//nolint:forcetypeassert // the VM is the only backend that compiles
+	return compiled.(*unit), nil
+}
+
+// compileUnit compiles the given program and every block body within it.
+func compileUnit(root ast.Node) (*unit, error) {
+	u := &unit{chunks: make(map[ast.Node]*Chunk)}
+
+	pending := []ast.Node{root}
+	for len(pending) > 0 {
+		node := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if _, ok := u.chunks[node]; ok {
+			continue
+		}
+
+		chunk, err := Compile(node)
+		if err != nil {
+			return nil, err
+		}
+
+		u.chunks[node] = chunk
+		pending = append(pending, chunk.Exprs...)
+	}
+
+	u.root = u.chunks[root]
+
+	return u, nil
+}
+
+// New returns a new VM.
+func New() *VM {
+	return &VM{}
+}
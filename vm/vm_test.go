@@ -0,0 +1,440 @@
+package vm_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/polyscone/knight/interpreter"
+	"github.com/polyscone/knight/lexer"
+	"github.com/polyscone/knight/parser"
+	"github.com/polyscone/knight/value"
+	"github.com/polyscone/knight/vm"
+)
+
+type result struct {
+	value  value.Value
+	output string
+	err    error
+}
+
+// execute runs the given source with a fresh interpreter, using the VM if
+// useVM is true, and the tree-walk evaluator otherwise.
+func execute(t *testing.T, source, input string, useVM bool, opts ...interpreter.Option) result {
+	t.Helper()
+
+	l := lexer.New()
+	p := parser.New(l)
+	g := value.NewGlobalStore()
+	program, err := p.ParseFile(g, "test.kn", strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	opts = append(opts,
+		interpreter.WithStdin(strings.NewReader(input)),
+		interpreter.WithStdout(&stdout),
+		interpreter.WithStderr(io.Discard),
+		interpreter.WithSeed(1),
+	)
+	if useVM {
+		opts = append(opts, interpreter.WithBackend(vm.New()))
+	}
+
+	val, err := interpreter.New(g, p, opts...).Execute(program)
+
+	return result{value: val, output: stdout.String(), err: err}
+}
+
+func TestVM(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		source string
+		input  string
+	}{
+		{"literal", "1", ""},
+		{"arithmetic", "+ 1 * 2 - 10 / 9 % 7 3", ""},
+		{"exponent", "^ 2 10", ""},
+		{"string concatenation", `+ "foo" 123`, ""},
+		{"string repetition", `* "ab" 3`, ""},
+		{"comparisons", `; O < 1 2 ; O > "b" "a" : ? T T`, ""},
+		{"not", "! 0", ""},
+		{"and short circuits", `& 0 O "unreachable"`, ""},
+		{"and", `& 1 "yes"`, ""},
+		{"or short circuits", `| "first" O "unreachable"`, ""},
+		{"or", `| "" 2`, ""},
+		{"if true", `IF T "yes" "no"`, ""},
+		{"if false", `IF F "yes" "no"`, ""},
+		{"while", `; = i 0 ; WHILE < i 5 ; O i = i + i 1 : i`, ""},
+		{"while result", `WHILE F 1`, ""},
+		{"assign", `; = a 1 ; = b = c + a 1 : + b c`, ""},
+		{"block", `; = f BLOCK + x 1 ; = x 1 : CALL f`, ""},
+		{"literal block", `CALL BLOCK 7`, ""},
+		{"variable block", `; = x 2 ; = f BLOCK * x x : + f f`, ""},
+		{"recursion", `; = fib BLOCK IF < n 2 n ; = n - n 1 ; = a CALL fib ; = n - n 1 ; = b CALL fib ; = n + n 2 : + a b ; = n 15 : CALL fib`, ""},
+		{"call non-block", `CALL 3`, ""},
+		{"eval", `; = a 3 : EVAL "* a a"`, ""},
+		{"eval assigns globals", `; EVAL "= z 9" : z`, ""},
+		{"ascii", `; O ASCII 65 : ASCII "a"`, ""},
+		{"length", `LENGTH "hello"`, ""},
+		{"get", `GET "hello" 1 3`, ""},
+		{"substitute", `SUBSTITUTE "hello" 1 3 "ipp"`, ""},
+		{"dump", `DUMP "foo"`, ""},
+		{"lists", `; = l + @ "abc" ; O ^ l "-" ; O [ l ; O ] l : + , 1 * l 2`, ""},
+		{"head of empty list", `[ @`, ""},
+		{"output", `; OUTPUT "a\" : OUTPUT "b"`, ""},
+		{"prompt", `; = a PROMPT ; = b PROMPT : + a b`, "foo\nbar\n"},
+		{"random", `; O RANDOM : RANDOM`, ""},
+		{"noop", `: : 1`, ""},
+		{"quit", `; O "before" ; QUIT 3 : O "after"`, ""},
+		{"undefined variable", "; = x 1\n: + x y", ""},
+		{"divide by zero", "+ 1 / 2 0", ""},
+		{"inside called block", "; = f BLOCK\n  % 1 0\n: CALL f", ""},
+		{"inside variable block", "; = f BLOCK\n  % 1 0\n: + 1 f", ""},
+		{"inside eval", `EVAL "+ 1 a"`, ""},
+		{"eval parse error", `; = a "+ 1" : EVAL a`, ""},
+		{"assign to non-variable", `= 1 2`, ""},
+		{"block conversion", `+ 1 BLOCK 1`, ""},
+		{"block condition", `IF BLOCK 1 1 2`, ""},
+		{"deep tail recursion", `; = n 100000 ; = f BLOCK IF n (; = n - n 1 CALL f) n : CALL f`, ""},
+		{"tail call", "; = g BLOCK\n  % 1 0\n; = f BLOCK ; = x 1 : CALL g\n: + 1 CALL f", ""},
+		{"tail variable call", "; = g BLOCK\n  % 1 0\n; = f BLOCK IF T g 0\n: + 1 f", ""},
+		{"tail call after and", "; = g BLOCK\n  % 1 0\n; = f BLOCK & 1 g\n: + 1 f", ""},
+		{"non-tail call", "; = g BLOCK\n  % 1 0\n; = f BLOCK + 1 g\n: + 1 f", ""},
+		{"tail call of returned block", "; = g BLOCK BLOCK\n  % 1 0\n; = f BLOCK CALL g\n: + 1 CALL f", ""},
+		{"tail call in eval", "; = g BLOCK\n  % 1 0\n: EVAL \"g\"", ""},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			want := execute(t, tc.source, tc.input, false)
+			got := execute(t, tc.source, tc.input, true)
+
+			compare(t, want, got)
+		})
+	}
+}
+
+func TestVMStrict(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		source string
+	}{
+		{"call variable block", "; = f BLOCK + 1 2 : CALL f"},
+		{"variable block isn't called", "; = f BLOCK 1 ; = g f : CALL g"},
+		{"variable block conversion", "; = f BLOCK 1\n: + 1 f"},
+		{"call non-block", "; = f BLOCK 1\n: CALL CALL f"},
+		{"overflow inside block", "; = f BLOCK\n  * 65536 65536\n: CALL f"},
+		{"dump block", "DUMP BLOCK 1"},
+		{"literal beyond 32 bits", "OUTPUT 5000000000"},
+		{"block literal beyond 32 bits", "CALL BLOCK 5000000000"},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			want := execute(t, tc.source, "", false, interpreter.WithStrict(true))
+			got := execute(t, tc.source, "", true, interpreter.WithStrict(true))
+
+			compare(t, want, got)
+		})
+	}
+}
+
+func TestVMIntSemantics(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name      string
+		source    string
+		semantics interpreter.IntSemantics
+	}{
+		{"wrap32 literal", "OUTPUT 5000000000", interpreter.IntWrap32},
+		{"wrap32 literal equals sum", "? 5000000000 + 0 5000000000", interpreter.IntWrap32},
+		{"wrap32 block literal", "CALL BLOCK 5000000000", interpreter.IntWrap32},
+		{"wrap32 string", `< 0 "3000000000"`, interpreter.IntWrap32},
+		{"native big literal", "OUTPUT 18446744073709551617", interpreter.IntNative},
+		{"native big literal equals sum", "? 18446744073709551617 + 0 18446744073709551617", interpreter.IntNative},
+		{"checked64 big literal", "OUTPUT 18446744073709551617", interpreter.IntChecked64},
+		{"checked64 big block literal", "CALL BLOCK 18446744073709551617", interpreter.IntChecked64},
+		{"big literal", "OUTPUT 18446744073709551617", interpreter.IntBig},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			want := execute(t, tc.source, "", false, interpreter.WithIntSemantics(tc.semantics))
+			got := execute(t, tc.source, "", true, interpreter.WithIntSemantics(tc.semantics))
+
+			compare(t, want, got)
+		})
+	}
+}
+
+// compare checks that the VM produced the same result as the tree-walk
+// evaluator, including the location and stack trace of any error.
+func compare(t *testing.T, want, got result) {
+	t.Helper()
+
+	if !value.Equal(want.value, got.value) && (want.value != nil || got.value != nil) {
+		t.Errorf("want value %v, got %v", want.value, got.value)
+	}
+
+	if want.output != got.output {
+		t.Errorf("want output %q, got %q", want.output, got.output)
+	}
+
+	if (want.err == nil) != (got.err == nil) {
+		t.Fatalf("want error %v, got %v", want.err, got.err)
+	}
+
+	if want.err != nil && want.err.Error() != got.err.Error() {
+		t.Errorf("want error %q, got %q", want.err, got.err)
+	}
+
+	var wantRuntime, gotRuntime *interpreter.RuntimeError
+	if errors.As(want.err, &wantRuntime) {
+		if !errors.As(got.err, &gotRuntime) {
+			t.Fatalf("want *interpreter.RuntimeError, got %#v", got.err)
+		}
+
+		if want, got := wantRuntime.StackTrace(), gotRuntime.StackTrace(); want != got {
+			t.Errorf("want stack trace %q, got %q", want, got)
+		}
+	}
+}
+
+func TestVMMaxSteps(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name     string
+		source   string
+		maxSteps int
+		wantErr  bool
+	}{
+		{"infinite loop", "WHILE T 1", 1000, true},
+		{"infinite recursion through eval", `; = f "EVAL f" : EVAL f`, 1000, true},
+		{"infinite recursion through a block", `; = f BLOCK CALL f : CALL f`, 1000, true},
+		{"no limit", `; = i 0 : WHILE < i 1000 = i + i 1`, 0, false},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res := execute(t, tc.source, "", true, interpreter.WithMaxSteps(tc.maxSteps))
+			if got := errors.Is(res.err, interpreter.ErrBudgetExceeded); got != tc.wantErr {
+				t.Errorf("want budget exceeded %v, got error %v", tc.wantErr, res.err)
+			}
+		})
+	}
+}
+
+func TestVMMaxDepth(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name     string
+		source   string
+		maxDepth int
+		wantErr  bool
+	}{
+		{"recursion over limit", `; = n 200 ; = f BLOCK IF n (; = n - n 1 + 1 CALL f) 0 : CALL f`, 100, true},
+		{"recursion within limit", `; = n 50 ; = f BLOCK IF n (; = n - n 1 + 1 CALL f) 0 : CALL f`, 100, false},
+		{"infinite recursion through eval", `; = f "EVAL f" : EVAL f`, 100, true},
+		{"tail recursion", `; = n 200000 ; = f BLOCK IF n (; = n - n 1 CALL f) 0 : CALL f`, 10, false},
+		{"tail recursion through variables", `; = n 200000 ; = f BLOCK IF n (; = n - n 1 f) 0 : f`, 10, false},
+	}
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res := execute(t, tc.source, "", true, interpreter.WithMaxDepth(tc.maxDepth))
+			if got := errors.Is(res.err, interpreter.ErrMaxDepthExceeded); got != tc.wantErr {
+				t.Errorf("want max depth exceeded %v, got error %v", tc.wantErr, res.err)
+			}
+		})
+	}
+}
+
+func TestVMEvalCacheBounded(t *testing.T) {
+	t.Parallel()
+
+	// Every EVAL parses a distinct program with a block in it, which is called
+	// both from inside and outside of the program
+	source := `
+		; = i 0
+		; WHILE < i 100
+			; EVAL + + "; = f BLOCK + 1 " i " : CALL f"
+			; = r CALL f
+			: = i + i 1
+		: r
+	`
+
+	l := lexer.New()
+	p := parser.New(l)
+	g := value.NewGlobalStore()
+	program, err := p.Parse(g, strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := interpreter.New(g, p, interpreter.WithBackend(vm.New()))
+	i.Runtime().SetProgramCacheLimit(4)
+
+	got, err := i.Execute(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := value.NewInt(100); !value.Equal(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	if got := i.Runtime().ProgramStats().Len; got > 4 {
+		t.Errorf("want at most 4 cached entries, got %v", got)
+	}
+}
+
+func TestVMProgramsBounded(t *testing.T) {
+	t.Parallel()
+
+	// A long lived VM runs many distinct programs with the same runtime
+	v := vm.New()
+	rt := interpreter.NewRuntime(nil)
+	rt.SetProgramCacheLimit(4)
+
+	for n := 0; n < 100; n++ {
+		l := lexer.New()
+		p := parser.New(l, parser.WithHeap(rt.Heap()))
+		g := value.NewGlobalStore()
+		program, err := p.Parse(g, strings.NewReader(fmt.Sprintf("; = f BLOCK + 1 %v : CALL f", n)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := interpreter.New(g, p, interpreter.WithBackend(v), interpreter.WithRuntime(rt)).Execute(program)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := value.NewInt(n + 1); !value.Equal(want, got) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	}
+
+	// The compiled programs are held by the runtime's cache rather than the
+	// VM, so they're evicted and reset along with it
+	stats := rt.ProgramStats()
+	if stats.Len > 4 {
+		t.Errorf("want at most 4 cached entries, got %v", stats.Len)
+	}
+
+	if stats.Evictions == 0 {
+		t.Error("want compiled programs to be evicted, got no evictions")
+	}
+
+	rt.Reset()
+
+	if got := rt.ProgramStats().Len; got != 0 {
+		t.Errorf("want no cached entries after a reset, got %v", got)
+	}
+}
+
+func TestVMEvalBlockCompiledOnce(t *testing.T) {
+	t.Parallel()
+
+	// The block is created by EVAL but only called once its program has
+	// returned, so its body has to be compiled on its own
+	source := `
+		; EVAL "= f BLOCK + 1 2"
+		; = i 0
+		; WHILE < i 100
+			; = r CALL f
+			: = i + i 1
+		: r
+	`
+
+	l := lexer.New()
+	p := parser.New(l)
+	g := value.NewGlobalStore()
+	program, err := p.Parse(g, strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := interpreter.New(g, p, interpreter.WithBackend(vm.New()))
+
+	got, err := i.Execute(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := value.NewInt(3); !value.Equal(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	// The misses compile the program, parse the EVAL program, and compile the
+	// block's body, and every other call finds the compiled body in the cache
+	stats := i.Runtime().ProgramStats()
+	if stats.Misses != 3 {
+		t.Errorf("want 3 misses, got %v", stats.Misses)
+	}
+
+	if stats.Hits < 99 {
+		t.Errorf("want at least 99 hits, got %v", stats.Hits)
+	}
+}
+
+func TestVMKnightProgram(t *testing.T) {
+	t.Parallel()
+
+	b, err := os.ReadFile("../lexer/testdata/knight.kn")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The Knight interpreter written in Knight reads a file name and then
+	// loads the file with SYSTEM, so the file is scripted here
+	source := `; = i 0 ; WHILE < i 5 ; O i = i + i 1 : O "done"`
+	runner := func() interpreter.Option {
+		return interpreter.WithCommandRunner(&interpreter.ScriptedRunner{
+			Outputs: map[string]string{"cat test.kn": source},
+		})
+	}
+
+	want := execute(t, string(b), "test.kn\n", false, runner())
+	got := execute(t, string(b), "test.kn\n", true, runner())
+
+	if want.err != nil || got.err != nil {
+		t.Fatalf("want error %v, got %v", want.err, got.err)
+	}
+
+	if want.output != got.output {
+		t.Errorf("want output %q, got %q", want.output, got.output)
+	}
+
+	if !strings.HasSuffix(got.output, "4\ndone\n") {
+		t.Errorf("want output to end with the program's output, got %q", got.output)
+	}
+}